@@ -1,32 +1,100 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/promslog"
+	"github.com/prometheus/exporter-toolkit/web"
 
 	"github.com/scottjab/prom-azerothcore-exporter/config"
 	"github.com/scottjab/prom-azerothcore-exporter/internal/exporter"
+	"github.com/scottjab/prom-azerothcore-exporter/metrics"
 	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
 )
 
+// Build information, set with -ldflags "-X main.version=... -X main.revision=..."
+var (
+	version  = "dev"
+	revision = "unknown"
+)
+
 func main() {
-	cfg := config.Load()
+	configFile := flag.String("config.file", "", "Path to a YAML configuration file; environment variables override its settings")
+	logLevel := flag.String("log.level", "", "Only log messages with the given severity or above: debug, info, warn or error (default info)")
+	logFormat := flag.String("log.format", "", "Output format of log messages: logfmt or json (default logfmt)")
+	webConfigFile := flag.String("web.config.file", "", "Path to a web configuration file enabling TLS and authentication, see https://github.com/prometheus/exporter-toolkit/blob/master/docs/web-configuration.md")
+	collectorFlags := exporter.RegisterCollectorFlags(flag.CommandLine)
+	flag.Parse()
 
-	connections, err := database.NewConnections(cfg.Database.DSN)
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		log.Fatalf("Failed to connect to databases: %v", err)
+		fatal("Failed to load configuration", err)
 	}
-	defer connections.Close()
 
-	exp := exporter.NewExporter(connections)
-	defer exp.Close()
+	// Command line flags override the configured logging
+	if *logLevel != "" {
+		cfg.Log.Level = *logLevel
+	}
+	if *logFormat != "" {
+		cfg.Log.Format = *logFormat
+	}
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		fatal("Invalid logging configuration", err)
+	}
+	slog.SetDefault(logger)
+	if err := mysql.SetLogger(slog.NewLogLogger(logger.Handler(), slog.LevelError)); err != nil {
+		fatal("Failed to set database driver logger", err)
+	}
 
-	prometheus.MustRegister(exp)
+	slog.Info("Starting WoW Private Server Exporter", "version", version, "revision", revision)
+	prometheus.MustRegister(metrics.NewBuildInfo(version, revision))
 
-	http.Handle("/metrics", promhttp.Handler())
+	collectorNames, err := collectorFlags.Enabled(cfg.Collectors)
+	if err != nil {
+		fatal("Invalid collector selection", err)
+	}
+	if err := cfg.ValidateNames(exporter.CollectorNames(), exporter.MetricNames(cfg)); err != nil {
+		fatal("Invalid configuration", err)
+	}
+
+	// Unavailable databases do not stop the exporter; they are retried in
+	// the background and reported by wow_database_up
+	pool, err := database.NewPool(cfg.Database)
+	if err != nil {
+		fatal("Failed to open databases", err)
+	}
+
+	exp, err := exporter.NewExporter(pool, cfg, collectorNames)
+	if err != nil {
+		fatal("Failed to create exporter", err)
+	}
+	slog.Info("Enabled collectors", "collectors", strings.Join(collectorNames, ","))
+
+	// queries is the parent of every scrape and poll; cancelling it aborts
+	// their running queries
+	queries, cancelQueries := context.WithCancel(context.Background())
+	defer cancelQueries()
+	exp.Start(queries)
+
+	http.Handle("/metrics", exp.Handler())
+	http.Handle("/probe", exp.ProbeHandler())
+	http.HandleFunc("/-/healthy", func(w http.ResponseWriter, r *http.Request) {
+		exporter.WriteWithLog(w, []byte("Healthy\n"))
+	})
+	http.Handle("/-/ready", exp.ReadyHandler(cfg.Server.ReadyTimeout))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write([]byte(`
 			<html>
@@ -34,14 +102,82 @@ func main() {
 			<body>
 				<h1>WoW Private Server Exporter</h1>
 				<p><a href="/metrics">Metrics</a></p>
+				<p><a href="/-/healthy">Health</a> &middot; <a href="/-/ready">Readiness</a></p>
+				<p>Configured targets are scraped through <code>/probe?target=&lt;name&gt;</code></p>
 			</body>
 			</html>
 		`))
 		if err != nil {
-			log.Printf("Error writing response: %v", err)
+			slog.Error("Error writing response", "err", err)
 		}
 	})
 
-	log.Printf("Starting WoW Private Server Exporter on port %s", cfg.Server.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, nil))
+	if *webConfigFile != "" {
+		cfg.Server.WebConfigFile = *webConfigFile
+	}
+	systemdSocket := false
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &[]string{cfg.Server.ListenAddress},
+		WebSystemdSocket:   &systemdSocket,
+		WebConfigFile:      &cfg.Server.WebConfigFile,
+	}
+
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{
+		BaseContext: func(net.Listener) context.Context { return queries },
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- web.ListenAndServe(server, webFlags, logger)
+	}()
+
+	select {
+	case err := <-serveErr:
+		cancelQueries()
+		exp.Close()
+		fatal("Server stopped", err)
+	case <-signals.Done():
+	}
+	// A second signal terminates immediately
+	stop()
+
+	slog.Info("Shutting down, waiting for in-flight scrapes", "timeout", cfg.Server.ShutdownTimeout)
+	if err := shutdown(server, cfg.Server.ShutdownTimeout); err != nil {
+		slog.Warn("In-flight scrapes did not finish in time, cancelling their queries", "err", err)
+	}
+	cancelQueries()
+	exp.Close()
+	slog.Info("Shutdown complete")
+}
+
+// shutdown stops server from accepting connections and waits up to timeout
+// for the in-flight requests to finish
+func shutdown(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newLogger creates the logger selected by cfg
+func newLogger(cfg config.LogConfig) (*slog.Logger, error) {
+	level := &promslog.AllowedLevel{}
+	if err := level.Set(cfg.Level); err != nil {
+		return nil, err
+	}
+	format := &promslog.AllowedFormat{}
+	if err := format.Set(cfg.Format); err != nil {
+		return nil, err
+	}
+	return promslog.New(&promslog.Config{Level: level, Format: format}), nil
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
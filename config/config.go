@@ -1,64 +1,535 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the exporter
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
+	Database    DatabaseConfig    `yaml:"database"`
+	Server      ServerConfig      `yaml:"server"`
+	Scrape      ScrapeConfig      `yaml:"scrape"`
+	Collectors  CollectorsConfig  `yaml:"collectors"`
+	Redaction   RedactionConfig   `yaml:"redaction"`
+	Exclusions  ExclusionConfig   `yaml:"exclusions"`
+	Money       MoneyConfig       `yaml:"money"`
+	Cardinality CardinalityConfig `yaml:"cardinality"`
+	Log         LogConfig         `yaml:"log"`
+	// Targets are further servers scraped through /probe?target=<name>
+	Targets map[string]DatabaseConfig `yaml:"targets"`
 }
 
 // DatabaseConfig holds database connection settings
 type DatabaseConfig struct {
-	User     string
-	Password string
-	Host     string
-	Port     string
-	DSN      string
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	// DSN is the characters database DSN of realms that do not set their own,
+	// and the base other DSNs are derived from
+	DSN string `yaml:"dsn"`
+	// AuthDSN is the auth database DSN; when empty it is derived from DSN
+	AuthDSN string `yaml:"auth_dsn"`
+	// AuthDatabase is the auth database name used when deriving AuthDSN
+	AuthDatabase string `yaml:"auth_database"`
+	// CharactersDatabase is the characters database name used when deriving realm DSNs
+	CharactersDatabase string `yaml:"characters_database"`
+	// WorldDatabase is the world database name used when deriving realm DSNs
+	WorldDatabase string `yaml:"world_database"`
+	// PlayerbotsDatabase is the playerbots database name used when deriving
+	// realm DSNs; the playerbots database is not used when it is empty
+	PlayerbotsDatabase string `yaml:"playerbots_database"`
+	// PoolConfig holds the pool settings of every database
+	PoolConfig `yaml:",inline"`
+	// Pools overrides the pool settings per database, keyed by auth,
+	// characters, world or playerbots
+	Pools map[string]PoolConfig `yaml:"pools"`
+	// SchemaInterval is the time between reloads of the tables and columns
+	// of each database, which decide the collectors that can run
+	SchemaInterval time.Duration `yaml:"schema_interval"`
+	Realms         []RealmConfig `yaml:"realms"`
+}
+
+// PoolConfig holds the connection pool settings of a database. In Pools,
+// settings left at zero are taken from the settings of every database.
+type PoolConfig struct {
+	// MaxOpenConns limits the open connections, 0 means unlimited
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// MaxIdleConns limits the idle connections kept
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// ConnMaxLifetime closes connections after this time, 0 keeps them forever
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+// databaseNames are the databases whose pools can be configured separately
+var databaseNames = []string{"auth", "characters", "world", "playerbots"}
+
+// RealmConfig holds the connection settings of one realm
+type RealmConfig struct {
+	// ID is the realm ID in the auth realmlist
+	ID int `yaml:"id"`
+	// Name labels the metrics of the realm. When empty the name is looked
+	// up in the auth realmlist, and the realm is left out of the metrics
+	// until it is known, so that its series keep one label.
+	Name string `yaml:"name"`
+	// CharactersDSN is the DSN of the realm's characters database; when empty
+	// it is derived from the main DSN
+	CharactersDSN string `yaml:"characters_dsn"`
+	// CharactersDatabase overrides the characters database name when deriving CharactersDSN
+	CharactersDatabase string `yaml:"characters_database"`
+	// WorldDSN is the DSN of the realm's world database; when empty it is derived from CharactersDSN
+	WorldDSN string `yaml:"world_dsn"`
+	// WorldDatabase overrides the world database name when deriving WorldDSN
+	WorldDatabase string `yaml:"world_database"`
+	// PlayerbotsDSN is the DSN of the realm's optional playerbots database
+	PlayerbotsDSN string `yaml:"playerbots_dsn"`
+	// PlayerbotsDatabase overrides the playerbots database name when deriving PlayerbotsDSN
+	PlayerbotsDatabase string `yaml:"playerbots_database"`
 }
 
 // ServerConfig holds server settings
 type ServerConfig struct {
-	Port string
+	ListenAddress string `yaml:"listen_address"`
+	// WebConfigFile is the exporter-toolkit web configuration enabling TLS
+	// and basic auth; --web.config.file overrides it
+	WebConfigFile string `yaml:"web_config_file"`
+	// ReadyTimeout bounds the database pings of the readiness endpoint
+	ReadyTimeout time.Duration `yaml:"ready_timeout"`
+	// ShutdownTimeout bounds how long in-flight scrapes may run after a
+	// termination signal before their queries are cancelled
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// ScrapeConfig holds scrape timing settings
+type ScrapeConfig struct {
+	// Timeout is the default time a single collector may run
+	Timeout time.Duration `yaml:"timeout"`
+	// CollectorTimeouts overrides Timeout for individual collectors, keyed by collector name
+	CollectorTimeouts map[string]time.Duration `yaml:"collector_timeouts"`
+	// TimeoutOffset is subtracted from the Prometheus scrape timeout to leave time for the response
+	TimeoutOffset time.Duration `yaml:"timeout_offset"`
+	// Poll refreshes collectors in the background and serves scrapes from the latest snapshot
+	Poll bool `yaml:"poll"`
+	// Interval is the default time between background refreshes of a collector
+	Interval time.Duration `yaml:"interval"`
+	// CollectorIntervals overrides Interval for individual collectors, keyed by collector name
+	CollectorIntervals map[string]time.Duration `yaml:"collector_intervals"`
+}
+
+// CollectorsConfig selects which collectors run
+type CollectorsConfig struct {
+	// Enabled restricts the exporter to these collectors when not empty
+	Enabled []string `yaml:"enabled"`
+	// Disabled lists collectors that never run
+	Disabled []string `yaml:"disabled"`
+}
+
+// RedactionConfig selects how player-identifying label values are exported.
+// Each label class takes one of the modes off, hash, truncate or drop.
+type RedactionConfig struct {
+	// Salt keys the HMAC of hashed values and must be set when a class hashes
+	Salt string `yaml:"salt"`
+	// Character applies to character name labels
+	Character string `yaml:"character"`
+	// Account applies to account name labels
+	Account string `yaml:"account"`
+	// IP applies to IP address labels
+	IP string `yaml:"ip"`
+}
+
+// ExclusionConfig selects the characters left out of character metrics,
+// such as those of testers and game masters. A character matching any rule
+// is excluded. By default the names and ages excluded before the rules were
+// configurable are excluded, see defaultConfig.
+type ExclusionConfig struct {
+	// NamePatterns are regular expressions matched against character names
+	// by MySQL's REGEXP, in the dialect of the characters database server
+	NamePatterns []string `yaml:"name_patterns"`
+	// Accounts are IDs of accounts whose characters are excluded
+	Accounts []int `yaml:"accounts"`
+	// MinGMLevel excludes the characters of accounts whose account_access
+	// gmlevel on the realm is at least this level, 0 disables the rule
+	MinGMLevel int `yaml:"min_gm_level"`
+	// MinAge excludes characters created less than this long ago
+	MinAge time.Duration `yaml:"min_age"`
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
-	cfg := &Config{
+// MoneyConfig configures the money flow metrics read from log_money
+type MoneyConfig struct {
+	// LargeTransferGold is the amount in gold from which a single transfer
+	// is counted as large, 0 disables counting large transfers
+	LargeTransferGold int `yaml:"large_transfer_gold"`
+}
+
+// LogConfig selects the level and format of log messages
+type LogConfig struct {
+	// Level is the minimum severity logged: debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is logfmt or json
+	Format string `yaml:"format"`
+}
+
+// CardinalityConfig limits the number of series per metric. Series over the
+// limit are folded into an "other" series, or dropped when their values do
+// not add up.
+type CardinalityConfig struct {
+	// MaxSeries is the default series limit of every metric, 0 means unlimited
+	MaxSeries int `yaml:"max_series"`
+	// MetricMaxSeries overrides MaxSeries for individual metrics, keyed by metric name
+	MetricMaxSeries map[string]int `yaml:"metric_max_series"`
+}
+
+// redactionModes are the valid modes of a redaction class
+var redactionModes = []string{"off", "hash", "truncate", "drop"}
+
+// defaultConfig returns the configuration used when nothing else is set
+func defaultConfig() *Config {
+	return &Config{
 		Database: DatabaseConfig{
-			User:     os.Getenv("WOW_DB_USER"),
-			Password: os.Getenv("WOW_DB_PASS"),
-			Host:     os.Getenv("WOW_DB_HOST"),
-			Port:     getEnvOrDefault("WOW_DB_PORT", "3306"),
+			Port:          "3306",
+			AuthDatabase:  defaultAuthDatabase,
+			WorldDatabase: defaultWorldDatabase,
+			PoolConfig: PoolConfig{
+				MaxOpenConns:    5,
+				MaxIdleConns:    2,
+				ConnMaxLifetime: 5 * time.Minute,
+			},
+			SchemaInterval: 5 * time.Minute,
 		},
 		Server: ServerConfig{
-			Port: getEnvOrDefault("PORT", "7000"),
+			ListenAddress:   ":7000",
+			ReadyTimeout:    5 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Scrape: ScrapeConfig{
+			Timeout:            10 * time.Second,
+			CollectorTimeouts:  make(map[string]time.Duration),
+			TimeoutOffset:      500 * time.Millisecond,
+			Interval:           30 * time.Second,
+			CollectorIntervals: make(map[string]time.Duration),
+		},
+		Redaction: RedactionConfig{
+			Character: "off",
+			Account:   "off",
+			IP:        "off",
+		},
+		// Metrics are unlimited unless a limit is configured
+		Cardinality: CardinalityConfig{
+			MetricMaxSeries: make(map[string]int),
+		},
+		// The characters the exporter always left out before exclusions
+		// were configurable, so that upgrading does not change the counts
+		Exclusions: ExclusionConfig{
+			NamePatterns: []string{"test", "admin", "gm", "dev"},
+			MinAge:       24 * time.Hour,
+		},
+		Money: MoneyConfig{
+			LargeTransferGold: 10000,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "logfmt",
 		},
 	}
+}
+
+// Load loads configuration from the YAML file at path, if any, and then from
+// environment variables, which override the file. The result is validated.
+func Load(path string) (*Config, error) {
+	cfg := defaultConfig()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
 
-	// Check if DSN is provided directly
-	if dsn := os.Getenv("WOW_DB_DSN"); dsn != "" {
-		cfg.Database.DSN = dsn
-	} else {
-		cfg.Database.DSN = cfg.Database.buildDSN()
+	env := &envLoader{}
+	env.load(cfg)
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	if len(cfg.Database.Realms) == 0 {
+		cfg.Database.Realms = []RealmConfig{{ID: 1}}
+	}
+	if err := cfg.Database.resolveDSNs(); err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+	for name, target := range cfg.Targets {
+		target.inherit(&cfg.Database)
+		if len(target.Realms) == 0 {
+			target.Realms = []RealmConfig{{ID: 1}}
+		}
+		if err := target.resolveDSNs(); err != nil {
+			return nil, fmt.Errorf("invalid configuration of target %s: %w", name, err)
+		}
+		cfg.Targets[name] = target
 	}
 
-	return cfg
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
 }
 
-// buildDSN builds the database connection string from individual components
-func (c *DatabaseConfig) buildDSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/acore_characters?parseTime=true",
-		c.User, c.Password, c.Host, c.Port)
+// loadFile decodes the YAML file at path over the current configuration,
+// rejecting unknown keys
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration and returns every problem found
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.ListenAddress == "" {
+		errs = append(errs, errors.New("server.listen_address must not be empty"))
+	}
+	if c.Server.ReadyTimeout <= 0 {
+		errs = append(errs, errors.New("server.ready_timeout must be positive"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout must be positive"))
+	}
+	errs = append(errs, c.Database.validate("database")...)
+	for name, target := range c.Targets {
+		if name == "" {
+			errs = append(errs, errors.New("targets must not have an empty name"))
+		}
+		errs = append(errs, target.validate("targets."+name)...)
+	}
+
+	if c.Scrape.Timeout <= 0 {
+		errs = append(errs, errors.New("scrape.timeout must be positive"))
+	}
+	for name, timeout := range c.Scrape.CollectorTimeouts {
+		if timeout <= 0 {
+			errs = append(errs, fmt.Errorf("scrape.collector_timeouts.%s must be positive", name))
+		}
+	}
+	if c.Scrape.TimeoutOffset < 0 {
+		errs = append(errs, errors.New("scrape.timeout_offset must not be negative"))
+	}
+	if c.Scrape.Interval <= 0 {
+		errs = append(errs, errors.New("scrape.interval must be positive"))
+	}
+	for name, interval := range c.Scrape.CollectorIntervals {
+		if interval <= 0 {
+			errs = append(errs, fmt.Errorf("scrape.collector_intervals.%s must be positive", name))
+		}
+	}
+
+	errs = append(errs, c.Redaction.validate()...)
+	errs = append(errs, c.Exclusions.validate()...)
+	if c.Money.LargeTransferGold < 0 {
+		errs = append(errs, errors.New("money.large_transfer_gold must not be negative"))
+	}
+
+	if c.Cardinality.MaxSeries < 0 {
+		errs = append(errs, errors.New("cardinality.max_series must not be negative"))
+	}
+	for name, max := range c.Cardinality.MetricMaxSeries {
+		if max < 0 {
+			errs = append(errs, fmt.Errorf("cardinality.metric_max_series.%s must not be negative", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateNames checks that the per-collector scrape settings name one of
+// collectors and the per-metric series limits one of metrics. The names are
+// known to the exporter only, which passes them in.
+func (c *Config) ValidateNames(collectors, metrics []string) error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(c.Scrape.CollectorTimeouts)) {
+		if !slices.Contains(collectors, name) {
+			errs = append(errs, fmt.Errorf("scrape.collector_timeouts.%s: unknown collector, must be one of %s", name, strings.Join(collectors, ", ")))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Scrape.CollectorIntervals)) {
+		if !slices.Contains(collectors, name) {
+			errs = append(errs, fmt.Errorf("scrape.collector_intervals.%s: unknown collector, must be one of %s", name, strings.Join(collectors, ", ")))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Cardinality.MetricMaxSeries)) {
+		if !slices.Contains(metrics, name) {
+			errs = append(errs, fmt.Errorf("cardinality.metric_max_series.%s: unknown metric or a metric without series limit", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the redaction modes and that hashing has a salt
+func (c *RedactionConfig) validate() []error {
+	var errs []error
+	hashing := false
+	for _, class := range []struct{ name, mode string }{
+		{"character", c.Character},
+		{"account", c.Account},
+		{"ip", c.IP},
+	} {
+		if !slices.Contains(redactionModes, class.mode) {
+			errs = append(errs, fmt.Errorf("redaction.%s: unknown mode %q, must be one of %s", class.name, class.mode, strings.Join(redactionModes, ", ")))
+		}
+		hashing = hashing || class.mode == "hash"
+	}
+	if hashing && c.Salt == "" {
+		errs = append(errs, errors.New("redaction.salt must be set when a class is hashed"))
+	}
+	return errs
+}
+
+// validate checks that the name patterns are set and the limits are in
+// range. The patterns themselves are checked by the database server.
+func (c *ExclusionConfig) validate() []error {
+	var errs []error
+	for i, pattern := range c.NamePatterns {
+		if pattern == "" {
+			errs = append(errs, fmt.Errorf("exclusions.name_patterns[%d] must not be empty", i))
+		}
+	}
+	for i, id := range c.Accounts {
+		if id <= 0 {
+			errs = append(errs, fmt.Errorf("exclusions.accounts[%d] must be positive", i))
+		}
+	}
+	if c.MinGMLevel < 0 {
+		errs = append(errs, errors.New("exclusions.min_gm_level must not be negative"))
+	}
+	if c.MinAge < 0 {
+		errs = append(errs, errors.New("exclusions.min_age must not be negative"))
+	}
+	return errs
+}
+
+// validate checks the database settings, reporting problems under prefix
+func (c *DatabaseConfig) validate(prefix string) []error {
+	errs := c.PoolConfig.validate(prefix)
+	if c.SchemaInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%s.schema_interval must be positive", prefix))
+	}
+	for name, pool := range c.Pools {
+		if !slices.Contains(databaseNames, name) {
+			errs = append(errs, fmt.Errorf("%s.pools.%s: unknown database, must be one of %s", prefix, name, strings.Join(databaseNames, ", ")))
+		}
+		errs = append(errs, pool.validate(prefix+".pools."+name)...)
+	}
+
+	seen := make(map[int]bool)
+	for i, realm := range c.Realms {
+		if realm.ID <= 0 {
+			errs = append(errs, fmt.Errorf("%s.realms[%d].id must be positive", prefix, i))
+		}
+		if seen[realm.ID] {
+			errs = append(errs, fmt.Errorf("%s.realms[%d].id %d is configured more than once", prefix, i, realm.ID))
+		}
+		seen[realm.ID] = true
+	}
+	return errs
 }
 
-// getEnvOrDefault gets an environment variable or returns a default value
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// inherit fills the port, database names, pool settings and schema interval
+// a target leaves unset from the main database settings. Servers and
+// credentials are never inherited.
+func (c *DatabaseConfig) inherit(from *DatabaseConfig) {
+	c.Port = firstNonEmpty(c.Port, from.Port)
+	c.AuthDatabase = firstNonEmpty(c.AuthDatabase, from.AuthDatabase)
+	c.CharactersDatabase = firstNonEmpty(c.CharactersDatabase, from.CharactersDatabase)
+	c.WorldDatabase = firstNonEmpty(c.WorldDatabase, from.WorldDatabase)
+	c.PlayerbotsDatabase = firstNonEmpty(c.PlayerbotsDatabase, from.PlayerbotsDatabase)
+	if c.SchemaInterval == 0 {
+		c.SchemaInterval = from.SchemaInterval
 	}
-	return defaultValue
+
+	pools := make(map[string]PoolConfig)
+	for _, name := range databaseNames {
+		pools[name] = from.PoolFor(name).merge(c.PoolConfig).merge(c.Pools[name])
+	}
+	c.Pools = pools
+}
+
+// validate checks the pool settings, reporting problems under prefix
+func (c PoolConfig) validate(prefix string) []error {
+	var errs []error
+	if c.MaxOpenConns < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_open_conns must not be negative", prefix))
+	}
+	if c.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_idle_conns must not be negative", prefix))
+	}
+	if c.ConnMaxLifetime < 0 {
+		errs = append(errs, fmt.Errorf("%s.conn_max_lifetime must not be negative", prefix))
+	}
+	return errs
+}
+
+// PoolFor returns the pool settings of the named database
+func (c *DatabaseConfig) PoolFor(name string) PoolConfig {
+	return c.PoolConfig.merge(c.Pools[name])
+}
+
+// merge returns the pool settings overridden by the non-zero settings of override
+func (c PoolConfig) merge(override PoolConfig) PoolConfig {
+	if override.MaxOpenConns != 0 {
+		c.MaxOpenConns = override.MaxOpenConns
+	}
+	if override.MaxIdleConns != 0 {
+		c.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.ConnMaxLifetime != 0 {
+		c.ConnMaxLifetime = override.ConnMaxLifetime
+	}
+	return c
+}
+
+// TimeoutFor returns the timeout for the named collector
+func (c *ScrapeConfig) TimeoutFor(name string) time.Duration {
+	if timeout, ok := c.CollectorTimeouts[name]; ok {
+		return timeout
+	}
+	return c.Timeout
+}
+
+// IntervalFor returns the background refresh interval for the named collector
+func (c *ScrapeConfig) IntervalFor(name string) time.Duration {
+	if interval, ok := c.CollectorIntervals[name]; ok {
+		return interval
+	}
+	return c.Interval
+}
+
+// MaxSeriesFor returns the series limit of the named metric, 0 meaning unlimited
+func (c *CardinalityConfig) MaxSeriesFor(name string) int {
+	if max, ok := c.MetricMaxSeries[name]; ok {
+		return max
+	}
+	return c.MaxSeries
+}
+
+// buildDSN builds the database connection string from individual components
+func (c *DatabaseConfig) buildDSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+		c.User, c.Password, c.Host, c.Port, firstNonEmpty(c.CharactersDatabase, defaultCharactersDatabase))
 }
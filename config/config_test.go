@@ -0,0 +1,288 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes content to a configuration file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing config file: %v", err)
+	}
+	return path
+}
+
+// validConfig returns the default configuration with its DSNs resolved
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.Database.Realms = []RealmConfig{{ID: 1}}
+	if err := cfg.Database.resolveDSNs(); err != nil {
+		t.Fatalf("resolving DSNs: %v", err)
+	}
+	return cfg
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv("WOW_DB_USER", "exporter")
+	t.Setenv("WOW_DB_PASS", "secret")
+	t.Setenv("WOW_DB_HOST", "db")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("loading defaults: %v", err)
+	}
+	if cfg.Server.ListenAddress != ":7000" {
+		t.Errorf("listen address: got %q, want %q", cfg.Server.ListenAddress, ":7000")
+	}
+	if cfg.Cardinality.MaxSeries != 0 {
+		t.Errorf("max series: got %d, want unlimited", cfg.Cardinality.MaxSeries)
+	}
+	if len(cfg.Database.Realms) != 1 || cfg.Database.Realms[0].ID != 1 {
+		t.Fatalf("realms: got %+v, want realm 1 only", cfg.Database.Realms)
+	}
+	if got := strings.Join(cfg.Exclusions.NamePatterns, ","); got != "test,admin,gm,dev" || cfg.Exclusions.MinAge != 24*time.Hour {
+		t.Errorf("exclusions: got %+v, want the names and ages excluded by earlier versions", cfg.Exclusions)
+	}
+	if want := "exporter:secret@tcp(db:3306)/acore_auth?parseTime=true"; cfg.Database.AuthDSN != want {
+		t.Errorf("auth DSN: got %q, want %q", cfg.Database.AuthDSN, want)
+	}
+	if want := "exporter:secret@tcp(db:3306)/acore_world?parseTime=true"; cfg.Database.Realms[0].WorldDSN != want {
+		t.Errorf("world DSN: got %q, want %q", cfg.Database.Realms[0].WorldDSN, want)
+	}
+}
+
+func TestLoadExampleConfig(t *testing.T) {
+	cfg, err := Load("../config.example.yml")
+	if err != nil {
+		t.Fatalf("loading example config: %v", err)
+	}
+	if got := cfg.Scrape.TimeoutFor("battleground"); got != 30*time.Second {
+		t.Errorf("battleground timeout: got %v, want 30s", got)
+	}
+	if got := cfg.Cardinality.MaxSeriesFor("wow_online_players_by_level"); got != 200 {
+		t.Errorf("wow_online_players_by_level limit: got %d, want 200", got)
+	}
+	target, ok := cfg.Targets["community-b"]
+	if !ok {
+		t.Fatal("target community-b is missing")
+	}
+	if target.Port != "3306" || target.SchemaInterval != 5*time.Minute {
+		t.Errorf("target community-b did not inherit the port and schema interval: %+v", target)
+	}
+}
+
+func TestLoadTurnsOffDefaultExclusions(t *testing.T) {
+	path := writeConfig(t, "exclusions:\n  name_patterns: []\n  min_age: 0s\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	if len(cfg.Exclusions.NamePatterns) != 0 || cfg.Exclusions.MinAge != 0 {
+		t.Errorf("exclusions: got %+v, want none", cfg.Exclusions)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := writeConfig(t, "scrape:\n  timeuot: 10s\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "timeuot") {
+		t.Errorf("got error %v, want one naming the unknown key", err)
+	}
+}
+
+func TestLoadEnvironmentOverridesFile(t *testing.T) {
+	path := writeConfig(t, "scrape:\n  timeout: 10s\n  collector_timeouts:\n    players: 20s\ncardinality:\n  max_series: 100\n")
+	t.Setenv("WOW_COLLECTOR_TIMEOUT", "15s")
+	t.Setenv("WOW_COLLECTOR_TIMEOUT_ONLINE_CHARACTERS", "30s")
+	t.Setenv("WOW_COLLECTOR_INTERVAL_BATTLEGROUND", "5m")
+	t.Setenv("WOW_MAX_SERIES_WOW_ONLINE_PLAYERS_BY_LEVEL", "200")
+	t.Setenv("WOW_REALMS", "1,2")
+	t.Setenv("WOW_REALM_2_CHARACTERS_DATABASE", "ac_chars_realm2")
+	t.Setenv("WOW_REALM_2_NAME", "Outland")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	for name, want := range map[string]time.Duration{
+		"players":           20 * time.Second,
+		"online_characters": 30 * time.Second,
+		"guild":             15 * time.Second,
+	} {
+		if got := cfg.Scrape.TimeoutFor(name); got != want {
+			t.Errorf("%s timeout: got %v, want %v", name, got, want)
+		}
+	}
+	if got := cfg.Scrape.IntervalFor("battleground"); got != 5*time.Minute {
+		t.Errorf("battleground interval: got %v, want 5m", got)
+	}
+	if got := cfg.Cardinality.MaxSeriesFor("wow_online_players_by_level"); got != 200 {
+		t.Errorf("wow_online_players_by_level limit: got %d, want 200", got)
+	}
+	if got := cfg.Cardinality.MaxSeriesFor("wow_guild_members"); got != 100 {
+		t.Errorf("wow_guild_members limit: got %d, want 100", got)
+	}
+	if len(cfg.Database.Realms) != 2 || cfg.Database.Realms[1].CharactersDatabase != "ac_chars_realm2" || cfg.Database.Realms[1].Name != "Outland" {
+		t.Errorf("realms: got %+v, want realms 1 and 2", cfg.Database.Realms)
+	}
+}
+
+func TestLoadInvalidEnvironment(t *testing.T) {
+	t.Setenv("WOW_COLLECTOR_TIMEOUT_PLAYERS", "soon")
+	t.Setenv("WOW_MAX_SERIES", "many")
+	_, err := Load("")
+	if err == nil {
+		t.Fatal("got no error for invalid environment variables")
+	}
+	for _, key := range []string{"WOW_COLLECTOR_TIMEOUT_PLAYERS", "WOW_MAX_SERIES"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not name %s", err, key)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string
+	}{
+		{
+			name:   "empty listen address",
+			modify: func(cfg *Config) { cfg.Server.ListenAddress = "" },
+			want:   "server.listen_address must not be empty",
+		},
+		{
+			name:   "zero scrape timeout",
+			modify: func(cfg *Config) { cfg.Scrape.Timeout = 0 },
+			want:   "scrape.timeout must be positive",
+		},
+		{
+			name:   "negative collector timeout",
+			modify: func(cfg *Config) { cfg.Scrape.CollectorTimeouts["players"] = -time.Second },
+			want:   "scrape.collector_timeouts.players must be positive",
+		},
+		{
+			name:   "zero collector interval",
+			modify: func(cfg *Config) { cfg.Scrape.CollectorIntervals["players"] = 0 },
+			want:   "scrape.collector_intervals.players must be positive",
+		},
+		{
+			name:   "negative metric limit",
+			modify: func(cfg *Config) { cfg.Cardinality.MetricMaxSeries["wow_guild_members"] = -1 },
+			want:   "cardinality.metric_max_series.wow_guild_members must not be negative",
+		},
+		{
+			name:   "unknown redaction mode",
+			modify: func(cfg *Config) { cfg.Redaction.IP = "mask" },
+			want:   `redaction.ip: unknown mode "mask"`,
+		},
+		{
+			name:   "hashing without salt",
+			modify: func(cfg *Config) { cfg.Redaction.Character = "hash" },
+			want:   "redaction.salt must be set",
+		},
+		{
+			name:   "empty name pattern",
+			modify: func(cfg *Config) { cfg.Exclusions.NamePatterns = []string{""} },
+			want:   "exclusions.name_patterns[0] must not be empty",
+		},
+		{
+			name:   "unknown pool",
+			modify: func(cfg *Config) { cfg.Database.Pools = map[string]PoolConfig{"logs": {}} },
+			want:   "database.pools.logs: unknown database",
+		},
+		{
+			name:   "duplicate realm",
+			modify: func(cfg *Config) { cfg.Database.Realms = []RealmConfig{{ID: 1}, {ID: 1}} },
+			want:   "database.realms[1].id 1 is configured more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Errorf("the default configuration is invalid: %v", err)
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	collectors := []string{"battleground", "players"}
+	metrics := []string{"wow_online_players_by_level"}
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string
+	}{
+		{
+			name:   "known names",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "known names of every setting",
+			modify: func(cfg *Config) {
+				cfg.Scrape.CollectorTimeouts["battleground"] = time.Minute
+				cfg.Scrape.CollectorIntervals["players"] = time.Minute
+				cfg.Cardinality.MetricMaxSeries["wow_online_players_by_level"] = 200
+			},
+		},
+		{
+			name:   "unknown collector timeout",
+			modify: func(cfg *Config) { cfg.Scrape.CollectorTimeouts["battlegrounds"] = time.Minute },
+			want:   "scrape.collector_timeouts.battlegrounds: unknown collector, must be one of battleground, players",
+		},
+		{
+			name:   "unknown collector interval",
+			modify: func(cfg *Config) { cfg.Scrape.CollectorIntervals["player"] = time.Minute },
+			want:   "scrape.collector_intervals.player: unknown collector",
+		},
+		{
+			name:   "unknown metric",
+			modify: func(cfg *Config) { cfg.Cardinality.MetricMaxSeries["online_players_by_level"] = 200 },
+			want:   "cardinality.metric_max_series.online_players_by_level: unknown metric",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+			err := cfg.ValidateNames(collectors, metrics)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNamesOfEnvironment(t *testing.T) {
+	t.Setenv("WOW_COLLECTOR_TIMEOUT_PLAYER", "30s")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	err = cfg.ValidateNames([]string{"players"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "scrape.collector_timeouts.player: unknown collector") {
+		t.Errorf("got error %v, want one naming the misspelt collector", err)
+	}
+}
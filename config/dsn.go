@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Default database names of an AzerothCore installation
+const (
+	defaultCharactersDatabase = "acore_characters"
+	defaultAuthDatabase       = "acore_auth"
+	defaultWorldDatabase      = "acore_world"
+)
+
+// resolveDSNs fills in every DSN that was not configured explicitly. Missing
+// DSNs are derived from the main DSN, or from the realm's characters DSN for
+// world and playerbots databases, by replacing the database name only.
+func (c *DatabaseConfig) resolveDSNs() error {
+	var errs []error
+
+	if c.DSN == "" {
+		c.DSN = c.buildDSN()
+	}
+	if c.AuthDSN == "" {
+		dsn, err := withDatabase(c.DSN, c.AuthDatabase)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deriving auth DSN: %w", err))
+		}
+		c.AuthDSN = dsn
+	}
+
+	for i := range c.Realms {
+		if err := c.Realms[i].resolveDSNs(c); err != nil {
+			errs = append(errs, fmt.Errorf("realm %d: %w", c.Realms[i].ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolveDSNs fills in the DSNs of a realm, falling back to the database
+// names configured for all realms
+func (r *RealmConfig) resolveDSNs(db *DatabaseConfig) error {
+	var errs []error
+
+	if r.CharactersDSN == "" {
+		r.CharactersDSN = db.DSN
+		if name := firstNonEmpty(r.CharactersDatabase, db.CharactersDatabase); name != "" {
+			dsn, err := withDatabase(db.DSN, name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("deriving characters DSN: %w", err))
+			}
+			r.CharactersDSN = dsn
+		}
+	}
+
+	if r.WorldDSN == "" {
+		dsn, err := withDatabase(r.CharactersDSN, firstNonEmpty(r.WorldDatabase, db.WorldDatabase))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deriving world DSN: %w", err))
+		}
+		r.WorldDSN = dsn
+	}
+
+	// The playerbots database is optional and only used when named or given a DSN
+	if name := firstNonEmpty(r.PlayerbotsDatabase, db.PlayerbotsDatabase); r.PlayerbotsDSN == "" && name != "" {
+		dsn, err := withDatabase(r.CharactersDSN, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deriving playerbots DSN: %w", err))
+		}
+		r.PlayerbotsDSN = dsn
+	}
+
+	return errors.Join(errs...)
+}
+
+// withDatabase returns dsn with its database name replaced by name
+func withDatabase(dsn, name string) (string, error) {
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	parsed.DBName = name
+	return parsed.FormatDSN(), nil
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
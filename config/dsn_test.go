@@ -0,0 +1,206 @@
+package config
+
+import "testing"
+
+func TestWithDatabase(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		db   string
+		want string
+	}{
+		{
+			name: "no query string",
+			dsn:  "exporter:secret@tcp(db:3306)/acore_characters",
+			db:   "acore_auth",
+			want: "exporter:secret@tcp(db:3306)/acore_auth",
+		},
+		{
+			name: "parameters",
+			dsn:  "exporter:secret@tcp(db:3306)/acore_characters?parseTime=true&timeout=5s&tls=skip-verify",
+			db:   "acore_world",
+			want: "exporter:secret@tcp(db:3306)/acore_world?parseTime=true&timeout=5s&tls=skip-verify",
+		},
+		{
+			name: "custom name",
+			dsn:  "exporter:secret@tcp(db:3306)/acore_characters?parseTime=true",
+			db:   "ac_chars_realm2",
+			want: "exporter:secret@tcp(db:3306)/ac_chars_realm2?parseTime=true",
+		},
+		{
+			name: "no database",
+			dsn:  "exporter:secret@unix(/run/mysqld/mysqld.sock)/",
+			db:   "acore_auth",
+			want: "exporter:secret@unix(/run/mysqld/mysqld.sock)/acore_auth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withDatabase(tt.dsn, tt.db)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDatabaseInvalidDSN(t *testing.T) {
+	if _, err := withDatabase("exporter:secret@tcp(db:3306)", "acore_auth"); err == nil {
+		t.Error("got no error for a DSN without a database separator")
+	}
+}
+
+func TestResolveDSNs(t *testing.T) {
+	const base = "exporter:secret@tcp(db:3306)/"
+	tests := []struct {
+		name  string
+		db    DatabaseConfig
+		auth  string
+		realm RealmConfig
+	}{
+		{
+			name: "defaults",
+			db: DatabaseConfig{
+				DSN:           base + "acore_characters?parseTime=true",
+				AuthDatabase:  defaultAuthDatabase,
+				WorldDatabase: defaultWorldDatabase,
+				Realms:        []RealmConfig{{ID: 1}},
+			},
+			auth: base + "acore_auth?parseTime=true",
+			realm: RealmConfig{
+				ID:            1,
+				CharactersDSN: base + "acore_characters?parseTime=true",
+				WorldDSN:      base + "acore_world?parseTime=true",
+			},
+		},
+		{
+			name: "built from components",
+			db: DatabaseConfig{
+				User:          "exporter",
+				Password:      "secret",
+				Host:          "db",
+				Port:          "3306",
+				AuthDatabase:  defaultAuthDatabase,
+				WorldDatabase: defaultWorldDatabase,
+				Realms:        []RealmConfig{{ID: 1}},
+			},
+			auth: base + "acore_auth?parseTime=true",
+			realm: RealmConfig{
+				ID:            1,
+				CharactersDSN: base + "acore_characters?parseTime=true",
+				WorldDSN:      base + "acore_world?parseTime=true",
+			},
+		},
+		{
+			name: "custom names for all realms",
+			db: DatabaseConfig{
+				DSN:                base + "acore_characters",
+				AuthDatabase:       "ac_auth",
+				CharactersDatabase: "ac_chars",
+				WorldDatabase:      "ac_world",
+				PlayerbotsDatabase: "ac_playerbots",
+				Realms:             []RealmConfig{{ID: 1}},
+			},
+			auth: base + "ac_auth",
+			realm: RealmConfig{
+				ID:            1,
+				CharactersDSN: base + "ac_chars",
+				WorldDSN:      base + "ac_world",
+				PlayerbotsDSN: base + "ac_playerbots",
+			},
+		},
+		{
+			name: "realm characters name",
+			db: DatabaseConfig{
+				DSN:           base + "acore_characters?parseTime=true&timeout=5s",
+				AuthDatabase:  defaultAuthDatabase,
+				WorldDatabase: defaultWorldDatabase,
+				Realms:        []RealmConfig{{ID: 2, CharactersDatabase: "ac_chars_realm2"}},
+			},
+			auth: base + "acore_auth?parseTime=true&timeout=5s",
+			realm: RealmConfig{
+				ID:                 2,
+				CharactersDatabase: "ac_chars_realm2",
+				CharactersDSN:      base + "ac_chars_realm2?parseTime=true&timeout=5s",
+				WorldDSN:           base + "acore_world?parseTime=true&timeout=5s",
+			},
+		},
+		{
+			name: "realm world name",
+			db: DatabaseConfig{
+				DSN:           base + "acore_characters",
+				AuthDatabase:  defaultAuthDatabase,
+				WorldDatabase: defaultWorldDatabase,
+				Realms:        []RealmConfig{{ID: 2, WorldDatabase: "ac_world_realm2"}},
+			},
+			auth: base + "acore_auth",
+			realm: RealmConfig{
+				ID:            2,
+				CharactersDSN: base + "acore_characters",
+				WorldDatabase: "ac_world_realm2",
+				WorldDSN:      base + "ac_world_realm2",
+			},
+		},
+		{
+			name: "realm world DSN",
+			db: DatabaseConfig{
+				DSN:           base + "acore_characters",
+				AuthDatabase:  defaultAuthDatabase,
+				WorldDatabase: defaultWorldDatabase,
+				Realms:        []RealmConfig{{ID: 2, WorldDSN: "exporter:secret@tcp(world:3306)/acore_world"}},
+			},
+			auth: base + "acore_auth",
+			realm: RealmConfig{
+				ID:            2,
+				CharactersDSN: base + "acore_characters",
+				WorldDSN:      "exporter:secret@tcp(world:3306)/acore_world",
+			},
+		},
+		{
+			name: "realm characters DSN",
+			db: DatabaseConfig{
+				DSN:                base + "acore_characters",
+				AuthDatabase:       defaultAuthDatabase,
+				WorldDatabase:      defaultWorldDatabase,
+				PlayerbotsDatabase: "acore_playerbots",
+				Realms:             []RealmConfig{{ID: 2, CharactersDSN: "exporter:secret@tcp(realm2:3307)/acore_characters?parseTime=true"}},
+			},
+			auth: base + "acore_auth",
+			realm: RealmConfig{
+				ID:            2,
+				CharactersDSN: "exporter:secret@tcp(realm2:3307)/acore_characters?parseTime=true",
+				WorldDSN:      "exporter:secret@tcp(realm2:3307)/acore_world?parseTime=true",
+				PlayerbotsDSN: "exporter:secret@tcp(realm2:3307)/acore_playerbots?parseTime=true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := tt.db
+			if err := db.resolveDSNs(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if db.AuthDSN != tt.auth {
+				t.Errorf("auth DSN: got %q, want %q", db.AuthDSN, tt.auth)
+			}
+			if db.Realms[0] != tt.realm {
+				t.Errorf("realm:\ngot  %+v\nwant %+v", db.Realms[0], tt.realm)
+			}
+		})
+	}
+}
+
+func TestResolveDSNsInvalidRealmDSN(t *testing.T) {
+	db := DatabaseConfig{
+		DSN:           "exporter:secret@tcp(db:3306)/acore_characters",
+		AuthDatabase:  defaultAuthDatabase,
+		WorldDatabase: defaultWorldDatabase,
+		Realms:        []RealmConfig{{ID: 2, CharactersDSN: "not a dsn"}},
+	}
+	if err := db.resolveDSNs(); err == nil {
+		t.Error("got no error deriving the world DSN of an invalid characters DSN")
+	}
+}
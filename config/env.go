@@ -0,0 +1,253 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envLoader applies environment variable overrides to a configuration and
+// collects every invalid value it encounters
+type envLoader struct {
+	errs []error
+}
+
+// load overrides cfg with every environment variable that is set
+func (l *envLoader) load(cfg *Config) {
+	l.string("WOW_DB_USER", &cfg.Database.User)
+	l.string("WOW_DB_PASS", &cfg.Database.Password)
+	l.string("WOW_DB_HOST", &cfg.Database.Host)
+	l.string("WOW_DB_PORT", &cfg.Database.Port)
+	l.string("WOW_DB_DSN", &cfg.Database.DSN)
+	l.string("WOW_DB_AUTH_DSN", &cfg.Database.AuthDSN)
+	l.string("WOW_DB_AUTH_DATABASE", &cfg.Database.AuthDatabase)
+	l.string("WOW_DB_CHARACTERS_DATABASE", &cfg.Database.CharactersDatabase)
+	l.string("WOW_DB_WORLD_DATABASE", &cfg.Database.WorldDatabase)
+	l.string("WOW_DB_PLAYERBOTS_DATABASE", &cfg.Database.PlayerbotsDatabase)
+	l.pool("WOW_DB_", &cfg.Database.PoolConfig)
+	for _, name := range databaseNames {
+		l.pools("WOW_DB_"+strings.ToUpper(name)+"_", name, &cfg.Database.Pools)
+	}
+	l.duration("WOW_DB_SCHEMA_INTERVAL", &cfg.Database.SchemaInterval)
+	l.realms(&cfg.Database.Realms)
+
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Server.ListenAddress = ":" + port
+	}
+	l.string("WOW_LISTEN_ADDRESS", &cfg.Server.ListenAddress)
+	l.string("WOW_WEB_CONFIG_FILE", &cfg.Server.WebConfigFile)
+	l.duration("WOW_READY_TIMEOUT", &cfg.Server.ReadyTimeout)
+	l.duration("WOW_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	l.duration("WOW_COLLECTOR_TIMEOUT", &cfg.Scrape.Timeout)
+	l.durationsWithPrefix("WOW_COLLECTOR_TIMEOUT_", &cfg.Scrape.CollectorTimeouts)
+	l.duration("WOW_SCRAPE_TIMEOUT_OFFSET", &cfg.Scrape.TimeoutOffset)
+	l.bool("WOW_POLL", &cfg.Scrape.Poll)
+	l.duration("WOW_COLLECTOR_INTERVAL", &cfg.Scrape.Interval)
+	l.durationsWithPrefix("WOW_COLLECTOR_INTERVAL_", &cfg.Scrape.CollectorIntervals)
+
+	l.list("WOW_COLLECTORS", &cfg.Collectors.Enabled)
+	l.list("WOW_COLLECTORS_DISABLED", &cfg.Collectors.Disabled)
+
+	l.int("WOW_MAX_SERIES", &cfg.Cardinality.MaxSeries)
+	l.intsWithPrefix("WOW_MAX_SERIES_", &cfg.Cardinality.MetricMaxSeries)
+
+	l.string("WOW_LOG_LEVEL", &cfg.Log.Level)
+	l.string("WOW_LOG_FORMAT", &cfg.Log.Format)
+
+	l.string("WOW_REDACT_SALT", &cfg.Redaction.Salt)
+	l.string("WOW_REDACT_CHARACTER", &cfg.Redaction.Character)
+	l.string("WOW_REDACT_ACCOUNT", &cfg.Redaction.Account)
+	l.string("WOW_REDACT_IP", &cfg.Redaction.IP)
+
+	l.list("WOW_EXCLUDE_NAME_PATTERNS", &cfg.Exclusions.NamePatterns)
+	l.ints("WOW_EXCLUDE_ACCOUNTS", &cfg.Exclusions.Accounts)
+	l.int("WOW_EXCLUDE_MIN_GM_LEVEL", &cfg.Exclusions.MinGMLevel)
+	l.duration("WOW_EXCLUDE_MIN_AGE", &cfg.Exclusions.MinAge)
+
+	l.int("WOW_MONEY_LARGE_TRANSFER_GOLD", &cfg.Money.LargeTransferGold)
+}
+
+// string overrides target with an environment variable
+func (l *envLoader) string(key string, target *string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+	}
+}
+
+// int overrides target with an environment variable parsed as an integer
+func (l *envLoader) int(key string, target *int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid integer %q", key, value))
+		return
+	}
+	*target = i
+}
+
+// bool overrides target with an environment variable parsed as a boolean
+func (l *envLoader) bool(key string, target *bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid boolean %q", key, value))
+		return
+	}
+	*target = b
+}
+
+// duration overrides target with an environment variable parsed as a duration
+func (l *envLoader) duration(key string, target *time.Duration) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid duration %q", key, value))
+		return
+	}
+	*target = d
+}
+
+// durationsWithPrefix sets every environment variable starting with prefix as
+// a duration in target, keyed by the lower-cased remainder of the variable name
+func (l *envLoader) durationsWithPrefix(prefix string, target *map[string]time.Duration) {
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		var d time.Duration
+		l.duration(key, &d)
+		if d == 0 {
+			continue
+		}
+		if *target == nil {
+			*target = make(map[string]time.Duration)
+		}
+		(*target)[strings.ToLower(strings.TrimPrefix(key, prefix))] = d
+	}
+}
+
+// intsWithPrefix sets every environment variable starting with prefix as an
+// integer in target, keyed by the lower-cased remainder of the variable name
+func (l *envLoader) intsWithPrefix(prefix string, target *map[string]int) {
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(key, prefix) || value == "" {
+			continue
+		}
+		var i int
+		l.int(key, &i)
+		if *target == nil {
+			*target = make(map[string]int)
+		}
+		(*target)[strings.ToLower(strings.TrimPrefix(key, prefix))] = i
+	}
+}
+
+// list overrides target with a comma-separated environment variable, dropping empty entries
+func (l *envLoader) list(key string, target *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*target = list
+}
+
+// ints overrides target with a comma-separated list of integers
+func (l *envLoader) ints(key string, target *[]int) {
+	var items []string
+	l.list(key, &items)
+	if items == nil {
+		return
+	}
+	ints := make([]int, 0, len(items))
+	for _, item := range items {
+		i, err := strconv.Atoi(item)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("%s: invalid integer %q", key, item))
+			return
+		}
+		ints = append(ints, i)
+	}
+	*target = ints
+}
+
+// pool overrides the pool settings in target with the environment variables
+// <prefix>MAX_OPEN_CONNS, <prefix>MAX_IDLE_CONNS and <prefix>CONN_MAX_LIFETIME
+func (l *envLoader) pool(prefix string, target *PoolConfig) {
+	l.int(prefix+"MAX_OPEN_CONNS", &target.MaxOpenConns)
+	l.int(prefix+"MAX_IDLE_CONNS", &target.MaxIdleConns)
+	l.duration(prefix+"CONN_MAX_LIFETIME", &target.ConnMaxLifetime)
+}
+
+// pools overrides the pool settings of the named database in target, as pool
+// does, allocating the map when needed
+func (l *envLoader) pools(prefix, name string, target *map[string]PoolConfig) {
+	pool := (*target)[name]
+	l.pool(prefix, &pool)
+	if pool == (*target)[name] {
+		return
+	}
+	if *target == nil {
+		*target = make(map[string]PoolConfig)
+	}
+	(*target)[name] = pool
+}
+
+// realms overrides the realm list. WOW_REALMS holds comma-separated realm
+// IDs, each configured with WOW_REALM_<ID>_<DATABASE>_DSN or
+// WOW_REALM_<ID>_<DATABASE>_DATABASE for the characters, world and
+// playerbots databases and named by WOW_REALM_<ID>_NAME. Without it,
+// WOW_REALM_ID selects a single realm using the main DSN.
+func (l *envLoader) realms(target *[]RealmConfig) {
+	var ids []string
+	l.list("WOW_REALMS", &ids)
+	if len(ids) == 0 {
+		if value := os.Getenv("WOW_REALM_ID"); value != "" {
+			var id int
+			l.int("WOW_REALM_ID", &id)
+			realm := RealmConfig{ID: id}
+			l.string(fmt.Sprintf("WOW_REALM_%d_NAME", id), &realm.Name)
+			*target = []RealmConfig{realm}
+		}
+		return
+	}
+
+	var realms []RealmConfig
+	for _, value := range ids {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("WOW_REALMS: invalid realm ID %q", value))
+			continue
+		}
+		prefix := fmt.Sprintf("WOW_REALM_%d_", id)
+		realm := RealmConfig{ID: id}
+		l.string(prefix+"NAME", &realm.Name)
+		l.string(prefix+"CHARACTERS_DSN", &realm.CharactersDSN)
+		l.string(prefix+"CHARACTERS_DATABASE", &realm.CharactersDatabase)
+		l.string(prefix+"WORLD_DSN", &realm.WorldDSN)
+		l.string(prefix+"WORLD_DATABASE", &realm.WorldDatabase)
+		l.string(prefix+"PLAYERBOTS_DSN", &realm.PlayerbotsDSN)
+		l.string(prefix+"PLAYERBOTS_DATABASE", &realm.PlayerbotsDatabase)
+		realms = append(realms, realm)
+	}
+	*target = realms
+}
@@ -18,13 +18,18 @@
         pkgs = nixpkgs.legacyPackages.${system};
       in
       {
-        packages.default = pkgs.buildGoModule {
+        packages.default = pkgs.buildGoModule rec {
           pname = "prom-azerothcore-exporter";
           version = "1.0.0";
           src = ./.;
           subPackages = [ "cmd/exporter" ];
 
-          vendorHash = "sha256-km3t3EDL05VPVhXSqkh6xzbKv97I1vaxXa4llNs0VG8=";
+          ldflags = [
+            "-X main.version=${version}"
+            "-X main.revision=${self.shortRev or "dirty"}"
+          ];
+
+          vendorHash = "sha256-QtgFys4ctiDZ92dHb3RWOnBlV2utP5i3La7j2eE+D4c=";
 
           meta = with pkgs.lib; {
             description = "Prometheus exporter for WoW private servers running AzerothCore";
@@ -115,6 +120,12 @@
               };
             };
 
+            webConfigFile = mkOption {
+              type = types.nullOr types.path;
+              default = null;
+              description = "Web configuration file enabling TLS and basic auth on the metrics endpoint.";
+            };
+
             environmentFile = mkOption {
               type = types.nullOr types.path;
               default = null;
@@ -179,7 +190,7 @@
                     "WOW_DB_HOST=${cfg.database.host}"
                     "WOW_DB_PORT=${toString cfg.database.port}"
                     "PORT=${toString cfg.port}"
-                  ];
+                  ] ++ optional (cfg.webConfigFile != null) "WOW_WEB_CONFIG_FILE=${cfg.webConfigFile}";
                 }
                 // (
                   if cfg.environmentFile != null then
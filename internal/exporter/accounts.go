@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("accounts", newAccountsCollector)
+}
+
+// accountsCollector exports account metrics from the auth database
+type accountsCollector struct {
+	total  *prometheus.Desc
+	online *prometheus.Desc
+	banned *prometheus.Desc
+	gm     *prometheus.Desc
+}
+
+func newAccountsCollector(cfg *config.Config) Collector {
+	return &accountsCollector{
+		total: newDesc(
+			"wow_accounts_total",
+			"Total number of accounts",
+			nil,
+		),
+		online: newDesc(
+			"wow_accounts_online",
+			"Number of accounts currently online",
+			nil,
+		),
+		banned: newDesc(
+			"wow_accounts_banned",
+			"Number of banned accounts",
+			nil,
+		),
+		gm: newDesc(
+			"wow_gm_account_count",
+			"Number of accounts with GM level on a realm",
+			[]string{"realm"},
+		),
+	}
+}
+
+func (c *accountsCollector) Name() string        { return "accounts" }
+func (c *accountsCollector) Databases() []string { return []string{database.AuthDatabase} }
+
+func (c *accountsCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.AuthDatabase, Table: "account", Columns: []string{"online"}},
+		{Database: database.AuthDatabase, Table: "account_banned", Columns: []string{"active"}},
+		{Database: database.AuthDatabase, Table: "account_access", Columns: []string{"id", "gmlevel", "RealmID"}},
+	}
+}
+
+func (c *accountsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.online
+	ch <- c.banned
+	ch <- c.gm
+}
+
+func (c *accountsCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	// Query for total accounts (auth database)
+	var totalAccounts int
+	query := `SELECT COUNT(*) FROM account`
+	err := conns.Auth.QueryRowContext(ctx, query).Scan(&totalAccounts)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "total_accounts", err)
+	}
+	gauge(ch, c.total, float64(totalAccounts))
+
+	// Query for online accounts (auth database)
+	var onlineAccounts int
+	query = `SELECT COUNT(*) FROM account WHERE online = 1`
+	err = conns.Auth.QueryRowContext(ctx, query).Scan(&onlineAccounts)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "online_accounts", err)
+	}
+	gauge(ch, c.online, float64(onlineAccounts))
+
+	// Query for banned accounts (auth database)
+	var bannedAccounts int
+	query = `SELECT COUNT(*) FROM account_banned WHERE active = 1`
+	err = conns.Auth.QueryRowContext(ctx, query).Scan(&bannedAccounts)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "banned_accounts", err)
+	}
+	gauge(ch, c.banned, float64(bannedAccounts))
+
+	// Query for GM accounts per realm, including GMs of all realms (auth database)
+	query = `SELECT COUNT(DISTINCT id) FROM account_access WHERE gmlevel > 0 AND RealmID IN (-1, ?)`
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		var gmAccounts int
+		if err := conns.Auth.QueryRowContext(ctx, query, realm.ID).Scan(&gmAccounts); err != nil {
+			return queryFailed(database.AuthDatabase, "gm_accounts", err)
+		}
+		gauge(ch, c.gm, float64(gmAccounts), realm.Name)
+		return nil
+	})
+}
@@ -0,0 +1,19 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAccountsCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`SELECT COUNT(*) FROM account`).WillReturnRows(count(120))
+	db.auth.ExpectQuery(`SELECT COUNT(*) FROM account WHERE online = 1`).WillReturnRows(count(14))
+	db.auth.ExpectQuery(`SELECT COUNT(*) FROM account_banned WHERE active = 1`).WillReturnRows(count(3))
+	db.auth.ExpectQuery(`FROM account_access WHERE gmlevel > 0 AND RealmID IN (-1, ?)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(DISTINCT id)"}).AddRow(2))
+
+	assertGolden(t, "accounts", conns, "accounts")
+}
@@ -0,0 +1,121 @@
+package exporter
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+// labelSeparator joins label values into accumulator keys; it cannot occur in valid UTF-8
+const labelSeparator = "\xff"
+
+// accumulator sums values per label set so that rows mapping to the same
+// labels (e.g. several races of one faction) are emitted as a single series
+type accumulator struct {
+	keys   []string
+	labels map[string][]string
+	values map[string]float64
+}
+
+// newAccumulator creates an empty accumulator
+func newAccumulator() *accumulator {
+	return &accumulator{
+		labels: make(map[string][]string),
+		values: make(map[string]float64),
+	}
+}
+
+// add adds value to the series identified by labelValues
+func (a *accumulator) add(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+	if _, exists := a.values[key]; !exists {
+		a.keys = append(a.keys, key)
+		a.labels[key] = labelValues
+	}
+	a.values[key] += value
+}
+
+// merge adds every series of other to a
+func (a *accumulator) merge(other *accumulator) {
+	for _, key := range other.keys {
+		a.add(other.values[key], other.labels[key]...)
+	}
+}
+
+// mergeLimited adds every series of other to a while a holds fewer than max
+// series. Once it is full, new series are added to one series per realm
+// whose labels but the first, the realm, are "other". A max of 0 means
+// unlimited.
+func (a *accumulator) mergeLimited(other *accumulator, max int) {
+	for _, key := range other.keys {
+		labels := other.labels[key]
+		if _, exists := a.values[key]; !exists && max > 0 && a.limited() >= max {
+			folded := make([]string, len(labels))
+			folded[0] = labels[0]
+			for i := 1; i < len(folded); i++ {
+				folded[i] = otherLabelValue
+			}
+			labels = folded
+		}
+		a.add(other.values[key], labels...)
+	}
+}
+
+// limited returns the number of series of a counted against a series limit,
+// leaving out the "other" series
+func (a *accumulator) limited() int {
+	n := 0
+	for _, labels := range a.labels {
+		if !isOther(labels) {
+			n++
+		}
+	}
+	return n
+}
+
+// isOther reports whether labels are those of an "other" series: every label
+// but the realm is "other"
+func isOther(labels []string) bool {
+	if len(labels) < 2 {
+		return false
+	}
+	for _, value := range labels[1:] {
+		if value != otherLabelValue {
+			return false
+		}
+	}
+	return true
+}
+
+// emit sends every accumulated series as a const gauge of desc
+func (a *accumulator) emit(ch chan<- prometheus.Metric, desc *prometheus.Desc) {
+	for _, key := range a.keys {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, a.values[key], a.labels[key]...)
+	}
+}
+
+// emitCounters sends every accumulated series as a const counter of desc
+func (a *accumulator) emitCounters(ch chan<- prometheus.Metric, desc *prometheus.Desc) {
+	for _, key := range a.keys {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, a.values[key], a.labels[key]...)
+	}
+}
+
+// gauge sends a single const gauge
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, value float64, labelValues ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+}
+
+// forEachRealm runs update for every realm and joins the errors, so that one
+// failing realm does not hide the metrics of the others
+func forEachRealm(conns *database.Connections, update func(realm *database.Realm) error) error {
+	var errs []error
+	for _, realm := range conns.Realms {
+		if err := update(realm); err != nil {
+			errs = append(errs, &realmError{realm: realm.Name, err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
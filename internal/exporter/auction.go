@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("auction", newAuctionCollector)
+}
+
+// auctionHouses maps auctionhouse.houseid to a readable house name
+var auctionHouses = map[int]string{1: "Alliance", 2: "Horde", 7: "Neutral"}
+
+// auctionCollector exports auction house metrics
+type auctionCollector struct {
+	count *prometheus.Desc
+}
+
+func newAuctionCollector(cfg *config.Config) Collector {
+	return &auctionCollector{
+		count: newDesc(
+			"wow_auction_count",
+			"Number of active auctions by house (faction)",
+			[]string{"realm", "house"},
+		),
+	}
+}
+
+func (c *auctionCollector) Name() string        { return "auction" }
+func (c *auctionCollector) Databases() []string { return []string{database.CharactersDatabase} }
+
+func (c *auctionCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.CharactersDatabase, Table: "auctionhouse", Columns: []string{"houseid"}},
+	}
+}
+
+func (c *auctionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.count
+}
+
+func (c *auctionCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, realm, ch)
+	})
+}
+
+func (c *auctionCollector) updateRealm(ctx context.Context, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// houseid: 7 (neutral), 1 (alliance), 2 (horde)
+	query := `SELECT houseid, COUNT(*) FROM auctionhouse GROUP BY houseid`
+	rows, err := realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "auctions_by_house", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var houseid, count int
+		if err := rows.Scan(&houseid, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "auctions_by_house", err)
+		}
+		house := auctionHouses[houseid]
+		if house == "" {
+			house = fmt.Sprintf("%d", houseid)
+		}
+		gauge(ch, c.count, float64(count), realm.Name, house)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "auctions_by_house", err)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAuctionCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT houseid, COUNT(*) FROM auctionhouse GROUP BY houseid`).
+		WillReturnRows(sqlmock.NewRows([]string{"houseid", "COUNT(*)"}).
+			AddRow(1, 40).
+			AddRow(2, 55).
+			AddRow(7, 12).
+			AddRow(9, 1))
+
+	assertGolden(t, "auction", conns, "auction")
+}
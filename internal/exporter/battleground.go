@@ -0,0 +1,458 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/constants"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("battleground", newBattlegroundCollector)
+}
+
+// battlegroundCollector exports battleground and PvP statistics
+type battlegroundCollector struct {
+	deserters       *prometheus.Desc
+	desertersByType *prometheus.Desc
+	randomQueue     *prometheus.Desc
+	stats           *prometheus.Desc
+	byType          *prometheus.Desc
+	byBracket       *prometheus.Desc
+	winsByFaction   *prometheus.Desc
+	playerStats     *prometheus.Desc
+	templates       *prometheus.Desc
+	templateDetails *prometheus.Desc
+	recent          *prometheus.Desc
+	active          *prometheus.Desc
+	activePlayers   *prometheus.Desc
+	activeTotal     *prometheus.Desc
+	// exclusions applies to the players in active battlegrounds
+	exclusions *exclusions
+}
+
+func newBattlegroundCollector(cfg *config.Config) Collector {
+	return &battlegroundCollector{
+		deserters: newDesc(
+			"wow_battleground_deserters",
+			"Number of battleground deserters",
+			[]string{"realm"},
+		),
+		desertersByType: newDesc(
+			"wow_battleground_deserters_by_type",
+			"Number of battleground deserters by type",
+			[]string{"realm", "desertion_type"},
+		),
+		randomQueue: newDesc(
+			"wow_random_battleground_queue",
+			"Number of players in random battleground queue",
+			[]string{"realm"},
+		),
+		stats: newValueDesc(
+			"wow_battleground_stats",
+			"Battleground statistics",
+			[]string{"realm", "stat"},
+		),
+		byType: newDesc(
+			"wow_battlegrounds_by_type",
+			"Number of battlegrounds by type",
+			[]string{"realm", "battleground_type"},
+		),
+		byBracket: newDesc(
+			"wow_battlegrounds_by_bracket",
+			"Number of battlegrounds by bracket",
+			[]string{"realm", "bracket"},
+		),
+		winsByFaction: newDesc(
+			"wow_battleground_wins_by_faction",
+			"Number of battleground wins by faction",
+			[]string{"realm", "faction"},
+		),
+		playerStats: newValueDesc(
+			"wow_battleground_player_stats",
+			"Battleground player statistics",
+			[]string{"realm", "stat"},
+		),
+		templates: newValueDesc(
+			"wow_battleground_templates",
+			"Battleground template information",
+			[]string{"realm", "template_id", "script_name"},
+		),
+		templateDetails: newValueDesc(
+			"wow_battleground_template_details",
+			"Detailed battleground template information",
+			[]string{"realm", "template_id", "name", "min_level", "max_level", "min_players", "max_players"},
+		),
+		recent: newDesc(
+			"wow_recent_battlegrounds",
+			"Recent battleground activity",
+			[]string{"realm", "time_period"},
+		),
+		active: newDesc(
+			"wow_active_battlegrounds",
+			"Number of active battlegrounds by type",
+			[]string{"realm", "battleground_name", "map_id"},
+		),
+		activePlayers: newDesc(
+			"wow_active_battleground_players",
+			"Number of players currently in battlegrounds by type",
+			[]string{"realm", "battleground_name", "map_id", "faction", "instance_id"},
+		),
+		activeTotal: newDesc(
+			"wow_active_battleground_total",
+			"Total number of players currently in battlegrounds",
+			[]string{"realm"},
+		),
+		exclusions: newExclusions(cfg.Exclusions),
+	}
+}
+
+func (c *battlegroundCollector) Name() string { return "battleground" }
+func (c *battlegroundCollector) Databases() []string {
+	return c.exclusions.databases(database.CharactersDatabase, database.WorldDatabase)
+}
+
+func (c *battlegroundCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.CharactersDatabase, Table: "battleground_deserters", Columns: []string{"type"}},
+		{Database: database.CharactersDatabase, Table: "character_battleground_random"},
+		{Database: database.CharactersDatabase, Table: "pvpstats_battlegrounds", Columns: []string{"id", "type", "bracket_id", "winner_faction", "date"}},
+		{Database: database.CharactersDatabase, Table: "pvpstats_players", Columns: []string{"battleground_id", "character_guid", "winner", "score_killing_blows", "score_deaths", "score_honorable_kills", "score_bonus_honor", "score_damage_done", "score_healing_done"}},
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"online", "map", "instance_id", "race"}},
+		{Database: database.WorldDatabase, Table: "battleground_template", Columns: []string{"ID", "ScriptName", "Comment", "MinPlayersPerTeam", "MaxPlayersPerTeam", "MinLvl", "MaxLvl", "Weight"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *battlegroundCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.deserters
+	ch <- c.desertersByType
+	ch <- c.randomQueue
+	ch <- c.stats
+	ch <- c.byType
+	ch <- c.byBracket
+	ch <- c.winsByFaction
+	ch <- c.playerStats
+	ch <- c.templates
+	ch <- c.templateDetails
+	ch <- c.recent
+	ch <- c.active
+	ch <- c.activePlayers
+	ch <- c.activeTotal
+}
+
+func (c *battlegroundCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *battlegroundCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Battleground deserters
+	var deserterCount int
+	err := realm.Characters.QueryRowContext(ctx, "SELECT COUNT(*) FROM battleground_deserters").Scan(&deserterCount)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_deserters", err)
+	}
+	gauge(ch, c.deserters, float64(deserterCount), realm.Name)
+
+	// Battleground deserters by type
+	rows, err := realm.Characters.QueryContext(ctx, `
+		SELECT type, COUNT(*) as count 
+		FROM battleground_deserters 
+		GROUP BY type
+	`)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_deserters_by_type", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var desertionType int
+		var count int
+		if err := rows.Scan(&desertionType, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "battleground_deserters_by_type", err)
+		}
+		gauge(ch, c.desertersByType, float64(count), realm.Name, constants.GetDesertionTypeName(desertionType))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_deserters_by_type", err)
+	}
+
+	// Random battleground queue
+	var queueCount int
+	err = realm.Characters.QueryRowContext(ctx, "SELECT COUNT(*) FROM character_battleground_random").Scan(&queueCount)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "random_battleground_queue", err)
+	}
+	gauge(ch, c.randomQueue, float64(queueCount), realm.Name)
+
+	// Battleground statistics
+	var totalBattlegrounds, totalPlayers int
+	err = realm.Characters.QueryRowContext(ctx, `
+		SELECT 
+			COUNT(DISTINCT id) as total_battlegrounds,
+			COUNT(DISTINCT character_guid) as total_players
+		FROM pvpstats_battlegrounds bg
+		LEFT JOIN pvpstats_players bp ON bg.id = bp.battleground_id
+	`).Scan(&totalBattlegrounds, &totalPlayers)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_stats", err)
+	}
+	gauge(ch, c.stats, float64(totalBattlegrounds), realm.Name, "total_battlegrounds")
+	gauge(ch, c.stats, float64(totalPlayers), realm.Name, "total_players")
+
+	// Battlegrounds by type
+	rows, err = realm.Characters.QueryContext(ctx, `
+		SELECT type, COUNT(*) as count 
+		FROM pvpstats_battlegrounds 
+		GROUP BY type
+	`)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battlegrounds_by_type", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var bgType int
+		var count int
+		if err := rows.Scan(&bgType, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "battlegrounds_by_type", err)
+		}
+		gauge(ch, c.byType, float64(count), realm.Name, realm.Names.Battleground(bgType))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "battlegrounds_by_type", err)
+	}
+
+	// Battlegrounds by bracket
+	rows, err = realm.Characters.QueryContext(ctx, `
+		SELECT bracket_id, COUNT(*) as count 
+		FROM pvpstats_battlegrounds 
+		GROUP BY bracket_id
+	`)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battlegrounds_by_bracket", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var bracket int
+		var count int
+		if err := rows.Scan(&bracket, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "battlegrounds_by_bracket", err)
+		}
+		gauge(ch, c.byBracket, float64(count), realm.Name, fmt.Sprintf("bracket_%d", bracket))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "battlegrounds_by_bracket", err)
+	}
+
+	// Battleground wins by faction
+	rows, err = realm.Characters.QueryContext(ctx, `
+		SELECT winner_faction, COUNT(*) as count 
+		FROM pvpstats_battlegrounds 
+		WHERE winner_faction IN (0, 1)
+		GROUP BY winner_faction
+	`)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_wins_by_faction", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var faction int
+		var count int
+		if err := rows.Scan(&faction, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "battleground_wins_by_faction", err)
+		}
+		factionName := "Horde"
+		if faction == 0 {
+			factionName = "Alliance"
+		}
+		gauge(ch, c.winsByFaction, float64(count), realm.Name, factionName)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_wins_by_faction", err)
+	}
+
+	// Battleground player statistics
+	var totalParticipants, totalWinners int
+	var avgKillingBlows, avgDeaths, avgHonorableKills, avgBonusHonor, avgDamageDone, avgHealingDone sql.NullFloat64
+	err = realm.Characters.QueryRowContext(ctx, `
+		SELECT 
+			COUNT(*) as total_participants,
+			COALESCE(SUM(CASE WHEN winner = 1 THEN 1 ELSE 0 END), 0) as total_winners,
+			AVG(score_killing_blows) as avg_killing_blows,
+			AVG(score_deaths) as avg_deaths,
+			AVG(score_honorable_kills) as avg_honorable_kills,
+			AVG(score_bonus_honor) as avg_bonus_honor,
+			AVG(score_damage_done) as avg_damage_done,
+			AVG(score_healing_done) as avg_healing_done
+		FROM pvpstats_players
+	`).Scan(&totalParticipants, &totalWinners, &avgKillingBlows, &avgDeaths, &avgHonorableKills, &avgBonusHonor, &avgDamageDone, &avgHealingDone)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "battleground_player_stats", err)
+	}
+	gauge(ch, c.playerStats, float64(totalParticipants), realm.Name, "total_participants")
+	gauge(ch, c.playerStats, float64(totalWinners), realm.Name, "total_winners")
+	if avgKillingBlows.Valid {
+		gauge(ch, c.playerStats, avgKillingBlows.Float64, realm.Name, "avg_killing_blows")
+	}
+	if avgDeaths.Valid {
+		gauge(ch, c.playerStats, avgDeaths.Float64, realm.Name, "avg_deaths")
+	}
+	if avgHonorableKills.Valid {
+		gauge(ch, c.playerStats, avgHonorableKills.Float64, realm.Name, "avg_honorable_kills")
+	}
+	if avgBonusHonor.Valid {
+		gauge(ch, c.playerStats, avgBonusHonor.Float64, realm.Name, "avg_bonus_honor")
+	}
+	if avgDamageDone.Valid {
+		gauge(ch, c.playerStats, avgDamageDone.Float64, realm.Name, "avg_damage_done")
+	}
+	if avgHealingDone.Valid {
+		gauge(ch, c.playerStats, avgHealingDone.Float64, realm.Name, "avg_healing_done")
+	}
+
+	// Battleground templates
+	rows, err = realm.World.QueryContext(ctx, `
+		SELECT ID, ScriptName, Comment, MinPlayersPerTeam, MaxPlayersPerTeam, MinLvl, MaxLvl, Weight
+		FROM battleground_template
+	`)
+	if err != nil {
+		return queryFailed(database.WorldDatabase, "battleground_templates", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var id, minPlayers, maxPlayers, minLvl, maxLvl, weight int
+		var scriptName, comment string
+		if err := rows.Scan(&id, &scriptName, &comment, &minPlayers, &maxPlayers, &minLvl, &maxLvl, &weight); err != nil {
+			return queryFailed(database.WorldDatabase, "battleground_templates", err)
+		}
+
+		// Create a more descriptive label
+		label := scriptName
+		if label == "" {
+			label = comment
+		}
+		if label == "" {
+			label = fmt.Sprintf("BG_%d", id)
+		}
+
+		gauge(ch, c.templates, float64(weight), realm.Name, fmt.Sprintf("%d", id), label)
+
+		// Add detailed template information
+		gauge(ch, c.templateDetails, float64(weight), realm.Name,
+			fmt.Sprintf("%d", id),
+			label,
+			fmt.Sprintf("%d", minLvl),
+			fmt.Sprintf("%d", maxLvl),
+			fmt.Sprintf("%d", minPlayers),
+			fmt.Sprintf("%d", maxPlayers),
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.WorldDatabase, "battleground_templates", err)
+	}
+
+	// Recent battleground activity (last 24 hours, 7 days, 30 days)
+	var last24h, last7d, last30d sql.NullInt64
+	err = realm.Characters.QueryRowContext(ctx, `
+		SELECT 
+			SUM(CASE WHEN date >= DATE_SUB(NOW(), INTERVAL 24 HOUR) THEN 1 ELSE 0 END) as last_24h,
+			SUM(CASE WHEN date >= DATE_SUB(NOW(), INTERVAL 7 DAY) THEN 1 ELSE 0 END) as last_7d,
+			SUM(CASE WHEN date >= DATE_SUB(NOW(), INTERVAL 30 DAY) THEN 1 ELSE 0 END) as last_30d
+		FROM pvpstats_battlegrounds
+	`).Scan(&last24h, &last7d, &last30d)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "recent_battleground_activity", err)
+	}
+	if last24h.Valid {
+		gauge(ch, c.recent, float64(last24h.Int64), realm.Name, "last_24h")
+	}
+	if last7d.Valid {
+		gauge(ch, c.recent, float64(last7d.Int64), realm.Name, "last_7d")
+	}
+	if last30d.Valid {
+		gauge(ch, c.recent, float64(last30d.Int64), realm.Name, "last_30d")
+	}
+
+	// Active battleground tracking
+	// Query for players currently in battleground maps
+	bgMaps := realm.Names.BattlegroundMaps()
+	if len(bgMaps) == 0 {
+		gauge(ch, c.activeTotal, 0, realm.Name)
+		return nil
+	}
+	args := make([]any, len(bgMaps))
+	for i, mapID := range bgMaps {
+		args[i] = mapID
+	}
+	included, includedArgs, err := c.exclusions.condition(ctx, conns.Auth, realm, "")
+	if err != nil {
+		return err
+	}
+	rows, err = realm.Characters.QueryContext(ctx, `
+		SELECT map, instance_id, race, COUNT(*) as count
+		FROM characters 
+		WHERE online = 1 AND map IN (`+placeholders(len(bgMaps))+`)
+		AND `+included+`
+		GROUP BY map, instance_id, race
+	`, append(args, includedArgs...)...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "active_battleground_players", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	totalActivePlayers := 0
+	activeBattlegrounds := make(map[int]int)
+	activePlayers := newAccumulator()
+
+	for rows.Next() {
+		var mapID, instanceID, race, count int
+		if err := rows.Scan(&mapID, &instanceID, &race, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "active_battleground_players", err)
+		}
+
+		// Use clean battleground name and instance ID as separate labels
+		if faction := realm.Names.Faction(race); faction != "" {
+			activePlayers.add(float64(count), realm.Name, battlegroundName(realm, mapID), fmt.Sprintf("%d", mapID), faction, fmt.Sprintf("%d", instanceID))
+		}
+		activeBattlegrounds[mapID] += count
+		totalActivePlayers += count
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "active_battleground_players", err)
+	}
+
+	activePlayers.emit(ch, c.activePlayers)
+
+	// Set active battleground counts
+	for mapID, count := range activeBattlegrounds {
+		gauge(ch, c.active, float64(count), realm.Name, battlegroundName(realm, mapID), fmt.Sprintf("%d", mapID))
+	}
+
+	// Set total active battleground players
+	gauge(ch, c.activeTotal, float64(totalActivePlayers), realm.Name)
+
+	return nil
+}
+
+// battlegroundName returns the name of a battleground map of realm
+func battlegroundName(realm *database.Realm, mapID int) string {
+	if name := realm.Names.Map(mapID); name != "" {
+		return name
+	}
+	return fmt.Sprintf("Unknown_BG_%d", mapID)
+}
+
+// placeholders returns n comma-separated query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
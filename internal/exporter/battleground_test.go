@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
+
+// expectBattlegroundStats expects the queries of the battleground collector
+// that precede the active battleground query
+func expectBattlegroundStats(db *fakeDatabases) {
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM battleground_deserters`).WillReturnRows(count(3))
+	db.characters.ExpectQuery(`SELECT type, COUNT(*) as count FROM battleground_deserters GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).
+			AddRow(0, 2).
+			AddRow(2, 1))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM character_battleground_random`).WillReturnRows(count(6))
+	db.characters.ExpectQuery(`LEFT JOIN pvpstats_players bp ON bg.id = bp.battleground_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"total_battlegrounds", "total_players"}).AddRow(12, 150))
+	db.characters.ExpectQuery(`SELECT type, COUNT(*) as count FROM pvpstats_battlegrounds GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).
+			AddRow(2, 8).
+			AddRow(30, 4))
+	db.characters.ExpectQuery(`GROUP BY bracket_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"bracket_id", "count"}).AddRow(7, 12))
+	db.characters.ExpectQuery(`WHERE winner_faction IN (0, 1)`).
+		WillReturnRows(sqlmock.NewRows([]string{"winner_faction", "count"}).
+			AddRow(0, 5).
+			AddRow(1, 7))
+	db.characters.ExpectQuery(`FROM pvpstats_players`).
+		WillReturnRows(sqlmock.NewRows([]string{"total_participants", "total_winners", "avg_killing_blows", "avg_deaths", "avg_honorable_kills", "avg_bonus_honor", "avg_damage_done", "avg_healing_done"}).
+			AddRow(150, 75, 2.5, 3.0, 12.0, 150.0, 40000.0, nil))
+	db.world.ExpectQuery(`FROM battleground_template`).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "ScriptName", "Comment", "MinPlayersPerTeam", "MaxPlayersPerTeam", "MinLvl", "MaxLvl", "Weight"}).
+			AddRow(2, "", "Warsong Gulch", 5, 10, 10, 80, 1).
+			AddRow(30, "bg_isle_of_conquest", "", 20, 40, 71, 80, 1))
+	db.characters.ExpectQuery(`SUM(CASE WHEN date >= DATE_SUB(NOW(), INTERVAL 24 HOUR) THEN 1 ELSE 0 END)`).
+		WillReturnRows(sqlmock.NewRows([]string{"last_24h", "last_7d", "last_30d"}).AddRow(2, 9, 12))
+}
+
+func TestBattlegroundCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectBattlegroundStats(db)
+	db.characters.ExpectQuery(`WHERE online = 1 AND map IN (?, ?, ?, ?, ?, ?)`).
+		WithArgs(30, 489, 529, 566, 607, 628).
+		WillReturnRows(sqlmock.NewRows([]string{"map", "instance_id", "race", "count"}).
+			AddRow(489, 3, 1, 4).
+			AddRow(489, 3, 4, 3).
+			AddRow(489, 3, 2, 6).
+			AddRow(30, 7, 5, 20))
+
+	assertGolden(t, "battleground", conns, "battleground")
+}
+
+func TestBattlegroundCollectorWithoutBattlegroundMaps(t *testing.T) {
+	conns, db := newTestConnections(t)
+	conns.Realms[0].Names = &dbc.Names{}
+	expectBattlegroundStats(db)
+
+	assertGolden(t, "battleground", conns, "battleground_no_maps")
+}
@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+// bots identifies the bot characters of a realm: random bots by character
+// GUID, and every character of an account the playerbots module manages
+type bots struct {
+	guids    []int64
+	accounts []int64
+}
+
+// loadBots reads the bots of realm from its playerbots database. Realms
+// without a playerbots database, or whose playerbots database lacks the
+// tables read here, have no bots, so that all their characters count as
+// players. While the playerbots database is down bots cannot be told from
+// players and loadBots returns database.ErrUnavailable.
+func loadBots(ctx context.Context, realm *database.Realm) (*bots, error) {
+	if realm.PlayerbotsDown {
+		return nil, fmt.Errorf("%w: realm %d %s", database.ErrUnavailable, realm.ID, database.PlayerbotsDatabase)
+	}
+	if realm.Playerbots == nil {
+		return &bots{}, nil
+	}
+
+	b := &bots{}
+	var err error
+	query := `SELECT DISTINCT bot FROM playerbots_random_bots`
+	if b.guids, err = queryIDs(ctx, realm.Playerbots, query); err != nil {
+		return noBots(realm, queryFailed(database.PlayerbotsDatabase, "random_bots", err))
+	}
+	// account_type is 1 for random bot accounts and 2 for addclass accounts
+	query = `SELECT account_id FROM playerbots_account_type WHERE account_type <> 0`
+	if b.accounts, err = queryIDs(ctx, realm.Playerbots, query); err != nil {
+		return noBots(realm, queryFailed(database.PlayerbotsDatabase, "bot_accounts", err))
+	}
+	return b, nil
+}
+
+// noBots returns no bots when err is a query of a missing playerbots table,
+// and err otherwise
+func noBots(realm *database.Realm, err error) (*bots, error) {
+	if mysqlErrorNumber(err) != erNoSuchTable {
+		return nil, err
+	}
+	slog.Debug("Counting every character as a player", "realm", realm.Name, "err", err)
+	return &bots{}, nil
+}
+
+// queryIDs returns the single integer column of every row of query
+func queryIDs(ctx context.Context, db database.Querier, query string, args ...any) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// condition returns an SQL expression over the characters table, whose
+// columns are qualified by prefix (e.g. "c."), that is 1 for bots and 0 for
+// players. The IDs are inlined as they can outnumber the placeholders a
+// statement may have.
+func (b *bots) condition(prefix string) string {
+	var terms []string
+	if len(b.guids) > 0 {
+		terms = append(terms, prefix+"guid IN ("+joinIDs(b.guids)+")")
+	}
+	if len(b.accounts) > 0 {
+		terms = append(terms, prefix+"account IN ("+joinIDs(b.accounts)+")")
+	}
+	if len(terms) == 0 {
+		return "0"
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+// joinIDs formats ids as a comma-separated list
+func joinIDs(ids []int64) string {
+	formatted := make([]string, len(ids))
+	for i, id := range ids {
+		formatted[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(formatted, ", ")
+}
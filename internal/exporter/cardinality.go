@@ -0,0 +1,281 @@
+package exporter
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+// otherLabelValue replaces the label values of series folded by the series limit
+const otherLabelValue = "other"
+
+// limitedDescs records the metrics of the Descs built by newDesc and
+// newValueDesc, which are the only ones the series limit applies to
+var limitedDescs sync.Map // *prometheus.Desc -> limitedDesc
+
+// limitedDesc is the metric a Desc describes
+type limitedDesc struct {
+	name string
+	// additive is set when the values of the series add up, so that series
+	// over the limit can be folded into one
+	additive bool
+}
+
+// newDesc builds the Desc of a collector metric whose series hold counts or
+// amounts that add up
+func newDesc(name, help string, labels []string) *prometheus.Desc {
+	desc := prometheus.NewDesc(name, help, labels, nil)
+	limitedDescs.Store(desc, limitedDesc{name: name, additive: true})
+	return desc
+}
+
+// newValueDesc builds the Desc of a collector metric whose series hold values
+// that do not add up, such as levels, latencies or timestamps
+func newValueDesc(name, help string, labels []string) *prometheus.Desc {
+	desc := prometheus.NewDesc(name, help, labels, nil)
+	limitedDescs.Store(desc, limitedDesc{name: name})
+	return desc
+}
+
+// seriesLimiter bounds the number of series each metric may have. Series
+// over the limit of an additive metric are folded into one series per realm
+// whose other labels are set to "other" and whose value is the sum of the
+// folded series; those of other metrics are dropped.
+type seriesLimiter struct {
+	cfg     config.CardinalityConfig
+	dropped *prometheus.CounterVec
+
+	// mu guards admitted, as collectors run concurrently
+	mu sync.Mutex
+	// admitted holds the label values of the counter series kept by each
+	// metric. Counter series keep their place for the life of the limiter,
+	// so that neither they nor the "other" series ever decrease.
+	admitted map[string]map[string]bool
+}
+
+func newSeriesLimiter(cfg config.CardinalityConfig, dropped *prometheus.CounterVec) *seriesLimiter {
+	return &seriesLimiter{
+		cfg:      cfg,
+		dropped:  dropped,
+		admitted: make(map[string]map[string]bool),
+	}
+}
+
+// limitedSeries is a gauge, counter or untyped series considered by the
+// limiter
+type limitedSeries struct {
+	metric    prometheus.Metric
+	dto       *dto.Metric
+	valueType prometheus.ValueType
+	value     float64
+}
+
+// limit applies the series limits to the metrics of one collector run,
+// keeping the series with the largest values of every gauge over its limit.
+// Counters keep the series admitted first instead, see limitCounters.
+// Histograms, summaries and metrics of Descs not built by newDesc or
+// newValueDesc are passed through unchanged.
+func (l *seriesLimiter) limit(metrics []prometheus.Metric) []prometheus.Metric {
+	byName := make(map[string][]limitedSeries)
+	var descs []limitedDesc
+	var result []prometheus.Metric
+	for _, m := range metrics {
+		desc, known := limitedDescs.Load(m.Desc())
+		if !known {
+			result = append(result, m)
+			continue
+		}
+		series, ok := newLimitedSeries(m)
+		if !ok {
+			result = append(result, m)
+			continue
+		}
+		name := desc.(limitedDesc).name
+		if _, exists := byName[name]; !exists {
+			descs = append(descs, desc.(limitedDesc))
+		}
+		byName[name] = append(byName[name], series)
+	}
+
+	for _, desc := range descs {
+		name := desc.name
+		series := byName[name]
+		max := l.cfg.MaxSeriesFor(name)
+		if max > 0 && series[0].valueType == prometheus.CounterValue {
+			result = append(result, l.limitCounters(name, series, max)...)
+			continue
+		}
+		if max <= 0 || len(series) <= max {
+			for _, s := range series {
+				result = append(result, s.metric)
+			}
+			continue
+		}
+
+		slices.SortStableFunc(series, func(a, b limitedSeries) int {
+			return cmp.Compare(b.value, a.value)
+		})
+		for _, s := range series[:max] {
+			result = append(result, s.metric)
+		}
+		if desc.additive {
+			result = append(result, fold(series[max:])...)
+		}
+		l.dropped.WithLabelValues(name).Add(float64(len(series) - max))
+	}
+	return result
+}
+
+// limitCounters limits the series of a counter to the first max series
+// seen. New series are admitted largest first while the metric has room,
+// and every other series is folded into "other": as the folded series are
+// counters that are never admitted later, the "other" series only grows.
+// Series a collector already folded into "other" are added to it as they
+// are, without taking a place or counting as dropped.
+func (l *seriesLimiter) limitCounters(name string, series []limitedSeries, max int) []prometheus.Metric {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	admitted := l.admitted[name]
+	if admitted == nil {
+		admitted = make(map[string]bool)
+		l.admitted[name] = admitted
+	}
+
+	slices.SortStableFunc(series, func(a, b limitedSeries) int {
+		return cmp.Compare(b.value, a.value)
+	})
+	var result []prometheus.Metric
+	var folded []limitedSeries
+	dropped := 0
+	for _, s := range series {
+		if isOtherSeries(s.dto) {
+			folded = append(folded, s)
+			continue
+		}
+		key := labelKey(s.dto)
+		if !admitted[key] && len(admitted) < max {
+			admitted[key] = true
+		}
+		if admitted[key] {
+			result = append(result, s.metric)
+		} else {
+			folded = append(folded, s)
+			dropped++
+		}
+	}
+	if len(folded) > 0 {
+		result = append(result, fold(folded)...)
+	}
+	if dropped > 0 {
+		l.dropped.WithLabelValues(name).Add(float64(dropped))
+	}
+	return result
+}
+
+// isOtherSeries reports whether every label of a series but the realm is
+// "other"
+func isOtherSeries(m *dto.Metric) bool {
+	values := make([]string, 0, len(m.Label))
+	for _, pair := range m.Label {
+		if pair.GetName() != "realm" {
+			values = append(values, pair.GetValue())
+		}
+	}
+	return len(values) > 0 && !slices.ContainsFunc(values, func(value string) bool {
+		return value != otherLabelValue
+	})
+}
+
+// labelKey joins the label values of a series
+func labelKey(m *dto.Metric) string {
+	values := make([]string, len(m.Label))
+	for i, pair := range m.Label {
+		values[i] = pair.GetValue()
+	}
+	return strings.Join(values, labelSeparator)
+}
+
+// newLimitedSeries reads a gauge, counter or untyped metric
+func newLimitedSeries(m prometheus.Metric) (limitedSeries, bool) {
+	out := &dto.Metric{}
+	if err := m.Write(out); err != nil {
+		return limitedSeries{}, false
+	}
+	series := limitedSeries{metric: m, dto: out}
+	switch {
+	case out.Gauge != nil:
+		series.valueType, series.value = prometheus.GaugeValue, out.Gauge.GetValue()
+	case out.Counter != nil:
+		series.valueType, series.value = prometheus.CounterValue, out.Counter.GetValue()
+	case out.Untyped != nil:
+		series.valueType, series.value = prometheus.UntypedValue, out.Untyped.GetValue()
+	default:
+		return limitedSeries{}, false
+	}
+	return series, true
+}
+
+// fold sums series of one metric into one series per realm
+func fold(series []limitedSeries) []prometheus.Metric {
+	folded := make(map[string]*foldedMetric)
+	var keys []string
+	for _, s := range series {
+		labels := make([]*dto.LabelPair, 0, len(s.dto.Label))
+		var key strings.Builder
+		for _, pair := range s.dto.Label {
+			value := otherLabelValue
+			if pair.GetName() == "realm" {
+				value = pair.GetValue()
+				key.WriteString(value)
+			}
+			labels = append(labels, &dto.LabelPair{Name: pair.Name, Value: &value})
+		}
+
+		m, exists := folded[key.String()]
+		if !exists {
+			m = &foldedMetric{desc: s.metric.Desc(), labels: labels, valueType: s.valueType}
+			folded[key.String()] = m
+			keys = append(keys, key.String())
+		}
+		m.value += s.value
+	}
+
+	metrics := make([]prometheus.Metric, 0, len(keys))
+	for _, key := range keys {
+		metrics = append(metrics, folded[key])
+	}
+	return metrics
+}
+
+// foldedMetric is the "other" series of a metric over its series limit
+type foldedMetric struct {
+	desc      *prometheus.Desc
+	labels    []*dto.LabelPair
+	valueType prometheus.ValueType
+	value     float64
+}
+
+// Desc implements prometheus.Metric
+func (m *foldedMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+// Write implements prometheus.Metric
+func (m *foldedMetric) Write(out *dto.Metric) error {
+	out.Label = m.labels
+	value := m.value
+	switch m.valueType {
+	case prometheus.CounterValue:
+		out.Counter = &dto.Counter{Value: &value}
+	case prometheus.GaugeValue:
+		out.Gauge = &dto.Gauge{Value: &value}
+	default:
+		out.Untyped = &dto.Untyped{Value: &value}
+	}
+	return nil
+}
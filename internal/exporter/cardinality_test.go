@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+// limitedValues runs the metrics through a limiter keeping two series per
+// metric, and returns the value of each remaining series by the value of its
+// label, along with the number of series counted as dropped
+func limitedValues(t *testing.T, metrics []prometheus.Metric, label string) (map[string]float64, float64) {
+	t.Helper()
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"metric"})
+	limiter := newSeriesLimiter(config.CardinalityConfig{MaxSeries: 2}, dropped)
+
+	values := make(map[string]float64)
+	for _, m := range limiter.limit(metrics) {
+		out := &dto.Metric{}
+		if err := m.Write(out); err != nil {
+			t.Fatalf("writing metric: %v", err)
+		}
+		for _, pair := range out.Label {
+			if pair.GetName() == label {
+				values[pair.GetValue()] = out.Gauge.GetValue()
+			}
+		}
+	}
+	return values, testutil.ToFloat64(dropped)
+}
+
+func TestSeriesLimiterFoldsAdditiveMetrics(t *testing.T) {
+	desc := newDesc("test_additive", "Test", []string{"realm", "zone"})
+	var metrics []prometheus.Metric
+	for zone, count := range map[string]float64{"a": 5, "b": 4, "c": 2, "d": 1} {
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, count, "Azeroth", zone))
+	}
+
+	values, dropped := limitedValues(t, metrics, "zone")
+	want := map[string]float64{"a": 5, "b": 4, otherLabelValue: 3}
+	if len(values) != len(want) {
+		t.Fatalf("got series %v, want %v", values, want)
+	}
+	for zone, value := range want {
+		if values[zone] != value {
+			t.Errorf("zone %s: got %v, want %v", zone, values[zone], value)
+		}
+	}
+	if dropped != 2 {
+		t.Errorf("got %v dropped series, want 2", dropped)
+	}
+}
+
+func TestSeriesLimiterDropsValueMetrics(t *testing.T) {
+	desc := newValueDesc("test_value", "Test", []string{"realm", "character"})
+	var metrics []prometheus.Metric
+	for character, level := range map[string]float64{"a": 80, "b": 70, "c": 60, "d": 1} {
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, level, "Azeroth", character))
+	}
+
+	values, dropped := limitedValues(t, metrics, "character")
+	want := map[string]float64{"a": 80, "b": 70}
+	if len(values) != len(want) {
+		t.Fatalf("got series %v, want %v", values, want)
+	}
+	for character, value := range want {
+		if values[character] != value {
+			t.Errorf("character %s: got %v, want %v", character, values[character], value)
+		}
+	}
+	if dropped != 2 {
+		t.Errorf("got %v dropped series, want 2", dropped)
+	}
+}
+
+func TestSeriesLimiterKeepsCounterSeriesAdmittedFirst(t *testing.T) {
+	desc := newDesc("test_counter_total", "Test", []string{"realm", "type"})
+	counters := func(values map[string]float64) []prometheus.Metric {
+		var metrics []prometheus.Metric
+		for moneyType, value := range values {
+			metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, "Azeroth", moneyType))
+		}
+		return metrics
+	}
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"metric"})
+	limiter := newSeriesLimiter(config.CardinalityConfig{MaxSeries: 2}, dropped)
+
+	// The first two series are admitted, and keep their place when larger
+	// series appear later
+	limiter.limit(counters(map[string]float64{"a": 1, "b": 2}))
+	limited := limiter.limit(counters(map[string]float64{"a": 1, "b": 3, "c": 50, "d": 7}))
+
+	values := make(map[string]float64)
+	for _, m := range limited {
+		out := &dto.Metric{}
+		if err := m.Write(out); err != nil {
+			t.Fatalf("writing metric: %v", err)
+		}
+		if out.Counter == nil {
+			t.Fatalf("got a series of type %v, want a counter", out)
+		}
+		for _, pair := range out.Label {
+			if pair.GetName() == "type" {
+				values[pair.GetValue()] = out.Counter.GetValue()
+			}
+		}
+	}
+	want := map[string]float64{"a": 1, "b": 3, otherLabelValue: 57}
+	if len(values) != len(want) {
+		t.Fatalf("got series %v, want %v", values, want)
+	}
+	for moneyType, value := range want {
+		if values[moneyType] != value {
+			t.Errorf("type %s: got %v, want %v", moneyType, values[moneyType], value)
+		}
+	}
+	if got := testutil.ToFloat64(dropped); got != 2 {
+		t.Errorf("got %v dropped series, want 2", got)
+	}
+}
+
+func TestSeriesLimiterAddsCollectorOtherSeriesToOther(t *testing.T) {
+	desc := newDesc("test_folded_total", "Test", []string{"realm", "type"})
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"metric"})
+	limiter := newSeriesLimiter(config.CardinalityConfig{MaxSeries: 1}, dropped)
+
+	// The "other" series of the collector is the largest, but neither takes
+	// the place of "a" nor counts as dropped
+	limited := limiter.limit([]prometheus.Metric{
+		prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 1, "Azeroth", "a"),
+		prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 9, "Azeroth", otherLabelValue),
+		prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 2, "Azeroth", "b"),
+	})
+
+	values := make(map[string]float64)
+	for _, m := range limited {
+		out := &dto.Metric{}
+		if err := m.Write(out); err != nil {
+			t.Fatalf("writing metric: %v", err)
+		}
+		for _, pair := range out.Label {
+			if pair.GetName() == "type" {
+				if _, exists := values[pair.GetValue()]; exists {
+					t.Fatalf("got series of type %s twice", pair.GetValue())
+				}
+				values[pair.GetValue()] = out.Counter.GetValue()
+			}
+		}
+	}
+	want := map[string]float64{"b": 2, otherLabelValue: 10}
+	if len(values) != len(want) {
+		t.Fatalf("got series %v, want %v", values, want)
+	}
+	for moneyType, value := range want {
+		if values[moneyType] != value {
+			t.Errorf("type %s: got %v, want %v", moneyType, values[moneyType], value)
+		}
+	}
+	if got := testutil.ToFloat64(dropped); got != 1 {
+		t.Errorf("got %v dropped series, want 1", got)
+	}
+}
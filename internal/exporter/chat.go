@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("chat", newChatCollector)
+}
+
+// chatCollector exports chat channel and activity log metrics
+type chatCollector struct {
+	channels      *prometheus.Desc
+	channelBans   *prometheus.Desc
+	logsByType    *prometheus.Desc
+	moneyLogs     *prometheus.Desc
+	encounterLogs *prometheus.Desc
+	arenaLogs     *prometheus.Desc
+	ipActionLogs  *prometheus.Desc
+}
+
+func newChatCollector(cfg *config.Config) Collector {
+	return &chatCollector{
+		channels: newDesc(
+			"wow_channel_count",
+			"Number of chat channels",
+			[]string{"realm"},
+		),
+		channelBans: newDesc(
+			"wow_channel_bans",
+			"Number of channel bans",
+			[]string{"realm"},
+		),
+		logsByType: newDesc(
+			"wow_log_count",
+			"Number of log entries by type",
+			[]string{"type"},
+		),
+		moneyLogs: newDesc(
+			"wow_money_logs",
+			"Number of money transaction logs",
+			[]string{"realm"},
+		),
+		encounterLogs: newDesc(
+			"wow_encounter_logs",
+			"Number of encounter logs",
+			[]string{"realm"},
+		),
+		arenaLogs: newDesc(
+			"wow_arena_logs",
+			"Number of arena fight logs",
+			[]string{"realm"},
+		),
+		ipActionLogs: newDesc(
+			"wow_ip_action_logs",
+			"Number of IP action logs",
+			nil,
+		),
+	}
+}
+
+func (c *chatCollector) Name() string { return "chat" }
+func (c *chatCollector) Databases() []string {
+	return []string{database.AuthDatabase, database.CharactersDatabase}
+}
+
+func (c *chatCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.AuthDatabase, Table: "logs", Columns: []string{"type"}},
+		{Database: database.AuthDatabase, Table: "logs_ip_actions"},
+		{Database: database.CharactersDatabase, Table: "channels"},
+		{Database: database.CharactersDatabase, Table: "channels_bans"},
+		{Database: database.CharactersDatabase, Table: "log_money"},
+		{Database: database.CharactersDatabase, Table: "log_encounter"},
+		{Database: database.CharactersDatabase, Table: "log_arena_fights"},
+	}
+}
+
+func (c *chatCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.channels
+	ch <- c.channelBans
+	ch <- c.logsByType
+	ch <- c.moneyLogs
+	ch <- c.encounterLogs
+	ch <- c.arenaLogs
+	ch <- c.ipActionLogs
+}
+
+func (c *chatCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	// Log counts by type (auth database)
+	query := `SELECT type, COUNT(*) FROM logs GROUP BY type`
+	rows, err := conns.Auth.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "log_counts_by_type", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var logType string
+		var count int
+		if err := rows.Scan(&logType, &count); err != nil {
+			return queryFailed(database.AuthDatabase, "log_counts_by_type", err)
+		}
+		gauge(ch, c.logsByType, float64(count), logType)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.AuthDatabase, "log_counts_by_type", err)
+	}
+
+	// IP action logs (auth database)
+	var count int
+	query = `SELECT COUNT(*) FROM logs_ip_actions`
+	err = conns.Auth.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "ip_action_logs", err)
+	}
+	gauge(ch, c.ipActionLogs, float64(count))
+
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, realm, ch)
+	})
+}
+
+func (c *chatCollector) updateRealm(ctx context.Context, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Channel metrics (characters database)
+	query := `SELECT COUNT(*) FROM channels`
+	var count int
+	err := realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "channel_metrics", err)
+	}
+	gauge(ch, c.channels, float64(count), realm.Name)
+
+	// Channel bans (characters database)
+	query = `SELECT COUNT(*) FROM channels_bans`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "channel_bans", err)
+	}
+	gauge(ch, c.channelBans, float64(count), realm.Name)
+
+	// Money logs (characters database)
+	query = `SELECT COUNT(*) FROM log_money`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "money_logs", err)
+	}
+	gauge(ch, c.moneyLogs, float64(count), realm.Name)
+
+	// Encounter logs (characters database)
+	query = `SELECT COUNT(*) FROM log_encounter`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "encounter_logs", err)
+	}
+	gauge(ch, c.encounterLogs, float64(count), realm.Name)
+
+	// Arena logs (characters database)
+	query = `SELECT COUNT(*) FROM log_arena_fights`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "arena_logs", err)
+	}
+	gauge(ch, c.arenaLogs, float64(count), realm.Name)
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestChatCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`SELECT type, COUNT(*) FROM logs GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "COUNT(*)"}).
+			AddRow("chat", 500).
+			AddRow("gm", 12))
+	db.auth.ExpectQuery(`SELECT COUNT(*) FROM logs_ip_actions`).WillReturnRows(count(75))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM channels`).WillReturnRows(count(6))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM channels_bans`).WillReturnRows(count(1))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM log_money`).WillReturnRows(count(320))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM log_encounter`).WillReturnRows(count(18))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM log_arena_fights`).WillReturnRows(count(4))
+
+	assertGolden(t, "chat", conns, "chat")
+}
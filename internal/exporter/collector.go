@@ -1,206 +1,385 @@
 package exporter
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
 	"github.com/scottjab/prom-azerothcore-exporter/metrics"
 	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
 )
 
+// databasePool is the part of database.Pool the exporter uses, which tests
+// replace by fakes
+type databasePool interface {
+	CheckSchema(reqs []database.Requirement) ([]database.SchemaGap, error)
+	Connections(databases ...string) (*database.Connections, error)
+	Statuses() []database.Status
+	Ping(ctx context.Context) error
+	Close()
+}
+
 // Exporter implements the Prometheus Collector interface
 type Exporter struct {
-	connections *database.Connections
+	pool           databasePool
+	cfg            *config.Config
+	collectorNames []string
+	collectors     []Collector
+	scrape         config.ScrapeConfig
+	scrapeErrors   *prometheus.CounterVec
+	seriesDropped  *prometheus.CounterVec
+	limiter        *seriesLimiter
+
+	mu     sync.Mutex
+	states map[string]*collectorState
+
+	// pollers tracks the running pollers started by Start
+	pollers sync.WaitGroup
+
+	// probes holds the exporters of the targets probed so far; closed is
+	// set by Close so that targets opened afterwards are closed at once
+	probesMu sync.Mutex
+	probes   map[string]*Exporter
+	closed   bool
 }
 
-// NewExporter creates a new exporter instance
-func NewExporter(connections *database.Connections) *Exporter {
-	return &Exporter{
-		connections: connections,
+// collectorState is the outcome of the latest run of a collector
+type collectorState struct {
+	// metrics holds the last successful result; it is only kept when polling
+	metrics     []prometheus.Metric
+	success     bool
+	duration    time.Duration
+	lastSuccess time.Time
+	// disabled is the reason the collector does not run, empty when it runs
+	disabled string
+	// gaps holds the realms left out because they lack a table or column
+	// the collector needs
+	gaps []database.SchemaGap
+}
+
+// NewExporter creates a new exporter instance running the named collectors
+// against pool, configured by cfg
+func NewExporter(pool *database.Pool, cfg *config.Config, collectorNames []string) (*Exporter, error) {
+	return newExporter(pool, cfg, collectorNames)
+}
+
+// newExporter is NewExporter for any databasePool
+func newExporter(pool databasePool, cfg *config.Config, collectorNames []string) (*Exporter, error) {
+	collectors, err := newCollectors(collectorNames, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNamePatterns(pool, cfg.Exclusions); err != nil {
+		return nil, err
 	}
+	seriesDropped := metrics.NewSeriesDropped()
+	return &Exporter{
+		pool:           pool,
+		cfg:            cfg,
+		collectorNames: collectorNames,
+		collectors:     collectors,
+		scrape:         cfg.Scrape,
+		scrapeErrors:   metrics.NewScrapeErrors(),
+		seriesDropped:  seriesDropped,
+		limiter:        newSeriesLimiter(cfg.Cardinality, seriesDropped),
+		states:         make(map[string]*collectorState),
+		probes:         make(map[string]*Exporter),
+	}, nil
 }
 
-// Close closes the exporter and its database connections
+// Close closes the exporter, the exporters of probed targets and their
+// database connections. It waits for the pollers, so the context passed to
+// Start must be cancelled first.
 func (e *Exporter) Close() {
-	if e.connections != nil {
-		e.connections.Close()
+	e.pollers.Wait()
+
+	e.probesMu.Lock()
+	e.closed = true
+	for _, probe := range e.probes {
+		probe.Close()
 	}
+	e.probesMu.Unlock()
+
+	e.pool.Close()
 }
 
 // Collect implements prometheus.Collector
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	// Collect all metrics
-	if err := e.collectPlayerMetrics(); err != nil {
-		log.Printf("Error collecting player metrics: %v", err)
-	}
-	if err := e.collectMailMetrics(); err != nil {
-		log.Printf("Error collecting mail metrics: %v", err)
-	}
-	if err := e.collectAccountMetrics(); err != nil {
-		log.Printf("Error collecting account metrics: %v", err)
-	}
-	if err := e.collectServerMetrics(); err != nil {
-		log.Printf("Error collecting server metrics: %v", err)
-	}
-	if err := e.collectAuctionMetrics(); err != nil {
-		log.Printf("Error collecting auction metrics: %v", err)
-	}
-	if err := e.collectGuildMetrics(); err != nil {
-		log.Printf("Error collecting guild metrics: %v", err)
-	}
-	if err := e.collectMaxLevelCharMetrics(); err != nil {
-		log.Printf("Error collecting max level char metrics: %v", err)
-	}
-	if err := e.collectUnreadMailMetrics(); err != nil {
-		log.Printf("Error collecting unread mail metrics: %v", err)
-	}
-	if err := e.collectGMAccountMetrics(); err != nil {
-		log.Printf("Error collecting GM account metrics: %v", err)
-	}
-	if err := e.collectLastServerRestartMetrics(); err != nil {
-		log.Printf("Error collecting last server restart metrics: %v", err)
-	}
-	if err := e.collectBannedCharMetrics(); err != nil {
-		log.Printf("Error collecting banned char metrics: %v", err)
-	}
-	if err := e.collectChatMetrics(); err != nil {
-		log.Printf("Error collecting chat metrics: %v", err)
-	}
-	if err := e.collectInstanceMetrics(); err != nil {
-		log.Printf("Error collecting instance metrics: %v", err)
-	}
-	if err := e.collectNetworkMetrics(); err != nil {
-		log.Printf("Error collecting network metrics: %v", err)
-	}
-	if err := e.collectBattlegroundMetrics(); err != nil {
-		log.Printf("Error collecting battleground metrics: %v", err)
-	}
-
-	// Send all metrics
-	metrics.PlayersOnline.Collect(ch)
-	metrics.PlayersTotal.Collect(ch)
-	metrics.PlayersByLevel.Collect(ch)
-	metrics.PlayersByClass.Collect(ch)
-	metrics.OnlinePlayersByLevel.Collect(ch)
-	metrics.MailTotal.Collect(ch)
-	metrics.MailByFaction.Collect(ch)
-	metrics.MailWithItems.Collect(ch)
-	metrics.AccountsTotal.Collect(ch)
-	metrics.AccountsOnline.Collect(ch)
-	metrics.AccountsBanned.Collect(ch)
-	metrics.ServerUptime.Collect(ch)
-	metrics.ServerMaxPlayers.Collect(ch)
-	metrics.AuctionCount.Collect(ch)
-	metrics.GuildCount.Collect(ch)
-	metrics.MaxLevelCharCount.Collect(ch)
-	metrics.UnreadMailCount.Collect(ch)
-	metrics.GMAccountCount.Collect(ch)
-	metrics.LastServerRestart.Collect(ch)
-	metrics.BannedCharCount.Collect(ch)
-	metrics.ChannelCount.Collect(ch)
-	metrics.ChannelBans.Collect(ch)
-	metrics.LogCountByType.Collect(ch)
-	metrics.GuildEventCount.Collect(ch)
-	metrics.MoneyLogCount.Collect(ch)
-	metrics.EncounterLogCount.Collect(ch)
-	metrics.ArenaLogCount.Collect(ch)
-	metrics.IPActionLogCount.Collect(ch)
-	metrics.ActiveInstanceCount.Collect(ch)
-	metrics.InstancesByDifficulty.Collect(ch)
-	metrics.CompletedEncounters.Collect(ch)
-	metrics.InstanceResets.Collect(ch)
-	metrics.CharactersInInstances.Collect(ch)
-	metrics.LFGDataCount.Collect(ch)
-	metrics.LagReportsCount.Collect(ch)
-	metrics.InstanceSavesCount.Collect(ch)
-	metrics.PlayerLatencyStats.Collect(ch)
-	metrics.IPBannedCount.Collect(ch)
-	metrics.IPActionLogsByType.Collect(ch)
-	metrics.LagReportsByType.Collect(ch)
-	metrics.AverageLatency.Collect(ch)
-	metrics.HighLatencyPlayers.Collect(ch)
-	metrics.NetworkActivityByIP.Collect(ch)
-	metrics.BattlegroundDeserters.Collect(ch)
-	metrics.BattlegroundDesertersByType.Collect(ch)
-	metrics.RandomBattlegroundQueue.Collect(ch)
-	metrics.BattlegroundStats.Collect(ch)
-	metrics.BattlegroundsByType.Collect(ch)
-	metrics.BattlegroundsByBracket.Collect(ch)
-	metrics.BattlegroundWinsByFaction.Collect(ch)
-	metrics.BattlegroundPlayerStats.Collect(ch)
-	metrics.BattlegroundTemplates.Collect(ch)
-	metrics.BattlegroundTemplateDetails.Collect(ch)
-	metrics.RecentBattlegrounds.Collect(ch)
-	metrics.ActiveBattlegrounds.Collect(ch)
-	metrics.ActiveBattlegroundPlayers.Collect(ch)
-	metrics.ActiveBattlegroundTotal.Collect(ch)
+	e.collect(context.Background(), e.collectors, ch)
+}
+
+// collect reports the given collectors. When polling it serves their latest
+// snapshots; otherwise it runs them concurrently, each bounded by its own
+// timeout and by ctx.
+func (e *Exporter) collect(ctx context.Context, collectors []Collector, ch chan<- prometheus.Metric) {
+	if e.scrape.Poll {
+		for _, c := range collectors {
+			e.sendSnapshot(c.Name(), ch)
+		}
+		e.sendDatabaseStatus(ch)
+		e.scrapeErrors.Collect(ch)
+		e.seriesDropped.Collect(ch)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range collectors {
+		wg.Add(1)
+		go func(c Collector) {
+			defer wg.Done()
+			sendState(c.Name(), e.update(ctx, c), ch)
+		}(c)
+	}
+	wg.Wait()
+
+	e.sendDatabaseStatus(ch)
+	e.scrapeErrors.Collect(ch)
+	e.seriesDropped.Collect(ch)
+}
+
+// sendDatabaseStatus sends whether each database is available and the
+// statistics of its connection pool
+func (e *Exporter) sendDatabaseStatus(ch chan<- prometheus.Metric) {
+	for _, status := range e.pool.Statuses() {
+		up := 0.0
+		if status.Up {
+			up = 1
+		}
+		labels := []string{status.Database, status.Realm}
+		stats := status.Stats
+		ch <- prometheus.MustNewConstMetric(metrics.DatabaseUp, prometheus.GaugeValue, up, labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBMaxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBOpenConnections, prometheus.GaugeValue, float64(stats.OpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBInUseConnections, prometheus.GaugeValue, float64(stats.InUse), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBIdleConnections, prometheus.GaugeValue, float64(stats.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBWaitCount, prometheus.CounterValue, float64(stats.WaitCount), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBMaxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBMaxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), labels...)
+		ch <- prometheus.MustNewConstMetric(metrics.DBMaxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), labels...)
+	}
+}
+
+// update runs a single collector, records the outcome and returns it along
+// with the metrics the collector sent, which may be partial if it failed. A
+// collector whose databases are unavailable is skipped and counts as failed.
+// Realms whose databases lack a table or column the collector needs are left
+// out, and the collector is disabled when the auth database or every realm
+// lacks one.
+func (e *Exporter) update(ctx context.Context, c Collector) collectorState {
+	gaps, err := e.pool.CheckSchema(c.Requirements())
+	if err != nil {
+		return e.disable(c.Name(), err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.scrape.TimeoutFor(c.Name()))
+	defer cancel()
+
+	start := time.Now()
+	conns, err := e.pool.Connections(c.Databases()...)
+	if err == nil {
+		conns, err = withoutRealms(conns, gaps)
+	}
+	if err != nil {
+		return e.record(c.Name(), start, 0, err, gaps, nil)
+	}
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		var collected []prometheus.Metric
+		for m := range ch {
+			collected = append(collected, m)
+		}
+		done <- collected
+	}()
+
+	err = c.Update(ctx, conns, ch)
+	duration := time.Since(start)
+	close(ch)
+
+	return e.record(c.Name(), start, duration, err, gaps, e.limiter.limit(<-done))
+}
+
+// withoutRealms returns conns without the realms of gaps. When realms only
+// remain because those meeting the schema are down, ErrUnavailable is
+// returned.
+func withoutRealms(conns *database.Connections, gaps []database.SchemaGap) (*database.Connections, error) {
+	if len(gaps) == 0 {
+		return conns, nil
+	}
+	filtered := &database.Connections{Auth: conns.Auth}
+	for _, realm := range conns.Realms {
+		if !slices.ContainsFunc(gaps, func(gap database.SchemaGap) bool { return gap.RealmID == realm.ID }) {
+			filtered.Realms = append(filtered.Realms, realm)
+		}
+	}
+	if len(conns.Realms) > 0 && len(filtered.Realms) == 0 {
+		return nil, fmt.Errorf("%w: no realm meeting the schema is available", database.ErrUnavailable)
+	}
+	return filtered, nil
+}
+
+// record stores the outcome of a collector run, along with the realms it
+// left out, and returns it along with the metrics collected by the run
+func (e *Exporter) record(name string, start time.Time, duration time.Duration, err error, gaps []database.SchemaGap, collected []prometheus.Metric) collectorState {
+	switch {
+	case unavailable(err):
+		slog.Debug("Skipping collector", "collector", name, "err", err)
+	case err != nil:
+		logCollectorError(name, duration, err)
+		e.scrapeErrors.WithLabelValues(name).Inc()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(name)
+	if state.disabled != "" {
+		slog.Info("Enabling collector, its tables are available", "collector", name)
+		state.disabled = ""
+	}
+	if !slices.Equal(state.gaps, gaps) {
+		for _, gap := range gaps {
+			if !slices.Contains(state.gaps, gap) {
+				slog.Warn("Leaving realm out of collector, a table or column it needs is missing", "collector", name, "realm_id", gap.RealmID, "reason", gap.Missing)
+			}
+		}
+		state.gaps = gaps
+	}
+	state.success = err == nil
+	state.duration = duration
+	if err == nil {
+		state.lastSuccess = start
+		// Only polled collectors keep a snapshot, and a failed refresh keeps
+		// serving the previous one
+		if e.scrape.Poll {
+			state.metrics = collected
+		}
+	}
+
+	result := *state
+	result.metrics = collected
+	return result
+}
+
+// disable records that a collector does not run because of reason and
+// drops its snapshot
+func (e *Exporter) disable(name, reason string) collectorState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(name)
+	if state.disabled != reason {
+		slog.Warn("Disabling collector, a table or column it needs is missing", "collector", name, "reason", reason)
+	}
+	state.disabled = reason
+	state.gaps = nil
+	state.metrics = nil
+	return *state
+}
+
+// state returns the state of the named collector, creating it on first use.
+// e.mu must be held.
+func (e *Exporter) state(name string) *collectorState {
+	state, exists := e.states[name]
+	if !exists {
+		state = &collectorState{}
+		e.states[name] = state
+	}
+	return state
+}
+
+// sendSnapshot sends the latest snapshot of a polled collector. Nothing is
+// sent until the collector has run once.
+func (e *Exporter) sendSnapshot(name string, ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	state, exists := e.states[name]
+	var snapshot collectorState
+	if exists {
+		snapshot = *state
+	}
+	e.mu.Unlock()
+
+	if exists {
+		sendState(name, snapshot, ch)
+	}
+}
+
+// sendState sends the metrics of a collector run followed by its enabled,
+// success, duration and freshness self-metrics. Disabled collectors only
+// report why they are disabled; realms left out are reported alongside once
+// their name is known.
+func sendState(name string, state collectorState, ch chan<- prometheus.Metric) {
+	if state.disabled != "" {
+		ch <- prometheus.MustNewConstMetric(metrics.CollectorEnabled, prometheus.GaugeValue, 0, name, "", state.disabled)
+		return
+	}
+	for _, m := range state.metrics {
+		ch <- m
+	}
+
+	ch <- prometheus.MustNewConstMetric(metrics.CollectorEnabled, prometheus.GaugeValue, 1, name, "", "")
+	for _, gap := range state.gaps {
+		if gap.Realm == "" {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(metrics.CollectorEnabled, prometheus.GaugeValue, 0, name, gap.Realm, gap.Missing)
+	}
+	success := 0.0
+	if state.success {
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(metrics.CollectorDuration, prometheus.GaugeValue, state.duration.Seconds(), name)
+	ch <- prometheus.MustNewConstMetric(metrics.CollectorSuccess, prometheus.GaugeValue, success, name)
+	if !state.lastSuccess.IsZero() {
+		ch <- prometheus.MustNewConstMetric(metrics.CollectorLastSuccess, prometheus.GaugeValue, float64(state.lastSuccess.UnixNano())/1e9, name)
+	}
 }
 
 // Describe implements prometheus.Collector
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	metrics.PlayersOnline.Describe(ch)
-	metrics.PlayersTotal.Describe(ch)
-	metrics.PlayersByLevel.Describe(ch)
-	metrics.PlayersByClass.Describe(ch)
-	metrics.OnlinePlayersByLevel.Describe(ch)
-	metrics.MailTotal.Describe(ch)
-	metrics.MailByFaction.Describe(ch)
-	metrics.MailWithItems.Describe(ch)
-	metrics.AccountsTotal.Describe(ch)
-	metrics.AccountsOnline.Describe(ch)
-	metrics.AccountsBanned.Describe(ch)
-	metrics.ServerUptime.Describe(ch)
-	metrics.ServerMaxPlayers.Describe(ch)
-	metrics.AuctionCount.Describe(ch)
-	metrics.GuildCount.Describe(ch)
-	metrics.MaxLevelCharCount.Describe(ch)
-	metrics.UnreadMailCount.Describe(ch)
-	metrics.GMAccountCount.Describe(ch)
-	metrics.LastServerRestart.Describe(ch)
-	metrics.BannedCharCount.Describe(ch)
-	metrics.ChannelCount.Describe(ch)
-	metrics.ChannelBans.Describe(ch)
-	metrics.LogCountByType.Describe(ch)
-	metrics.GuildEventCount.Describe(ch)
-	metrics.MoneyLogCount.Describe(ch)
-	metrics.EncounterLogCount.Describe(ch)
-	metrics.ArenaLogCount.Describe(ch)
-	metrics.IPActionLogCount.Describe(ch)
-	metrics.ActiveInstanceCount.Describe(ch)
-	metrics.InstancesByDifficulty.Describe(ch)
-	metrics.CompletedEncounters.Describe(ch)
-	metrics.InstanceResets.Describe(ch)
-	metrics.CharactersInInstances.Describe(ch)
-	metrics.LFGDataCount.Describe(ch)
-	metrics.LagReportsCount.Describe(ch)
-	metrics.InstanceSavesCount.Describe(ch)
-	metrics.PlayerLatencyStats.Describe(ch)
-	metrics.IPBannedCount.Describe(ch)
-	metrics.IPActionLogsByType.Describe(ch)
-	metrics.LagReportsByType.Describe(ch)
-	metrics.AverageLatency.Describe(ch)
-	metrics.HighLatencyPlayers.Describe(ch)
-	metrics.NetworkActivityByIP.Describe(ch)
-	metrics.BattlegroundDeserters.Describe(ch)
-	metrics.BattlegroundDesertersByType.Describe(ch)
-	metrics.RandomBattlegroundQueue.Describe(ch)
-	metrics.BattlegroundStats.Describe(ch)
-	metrics.BattlegroundsByType.Describe(ch)
-	metrics.BattlegroundsByBracket.Describe(ch)
-	metrics.BattlegroundWinsByFaction.Describe(ch)
-	metrics.BattlegroundPlayerStats.Describe(ch)
-	metrics.BattlegroundTemplates.Describe(ch)
-	metrics.BattlegroundTemplateDetails.Describe(ch)
-	metrics.RecentBattlegrounds.Describe(ch)
-	metrics.ActiveBattlegrounds.Describe(ch)
-	metrics.ActiveBattlegroundPlayers.Describe(ch)
-	metrics.ActiveBattlegroundTotal.Describe(ch)
+	for _, c := range e.collectors {
+		c.Describe(ch)
+	}
+	ch <- metrics.CollectorEnabled
+	ch <- metrics.CollectorSuccess
+	ch <- metrics.CollectorDuration
+	ch <- metrics.CollectorLastSuccess
+	ch <- metrics.DatabaseUp
+	ch <- metrics.DBMaxOpenConnections
+	ch <- metrics.DBOpenConnections
+	ch <- metrics.DBInUseConnections
+	ch <- metrics.DBIdleConnections
+	ch <- metrics.DBWaitCount
+	ch <- metrics.DBWaitDuration
+	ch <- metrics.DBMaxIdleClosed
+	ch <- metrics.DBMaxIdleTimeClosed
+	ch <- metrics.DBMaxLifetimeClosed
+	e.scrapeErrors.Describe(ch)
+	e.seriesDropped.Describe(ch)
+}
+
+// filterCollectors returns the enabled collectors with the given names
+func (e *Exporter) filterCollectors(names []string) ([]Collector, error) {
+	var collectors []Collector
+	for _, name := range names {
+		i := slices.IndexFunc(e.collectors, func(c Collector) bool { return c.Name() == name })
+		if i < 0 {
+			return nil, fmt.Errorf("collector %q is not enabled", name)
+		}
+		collectors = append(collectors, e.collectors[i])
+	}
+	return collectors, nil
 }
 
 // Helper function for writing HTTP responses
 func WriteWithLog(w http.ResponseWriter, data []byte) {
 	if _, err := w.Write(data); err != nil {
-		log.Printf("Error writing response: %v", err)
+		slog.Error("Error writing response", "err", err)
 	}
 }
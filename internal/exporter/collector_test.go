@@ -0,0 +1,204 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
+
+// expectGuild declares the queries of one successful guild collector run
+func expectGuild(db *fakeDatabases) {
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild`).WillReturnRows(count(8))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild_eventlog`).WillReturnRows(count(230))
+}
+
+// expectServer declares the query of one server collector run, returning
+// after delay
+func expectServer(db *fakeDatabases, delay time.Duration) {
+	db.auth.ExpectQuery(`FROM uptime WHERE realmid = ?`).
+		WithArgs(1).
+		WillDelayFor(delay).
+		WillReturnRows(sqlmock.NewRows([]string{"starttime", "uptime", "maxplayers"}).AddRow(1700000000, 86400, 42))
+}
+
+func TestExporterRunsCollectors(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectGuild(db)
+	expectServer(db, 0)
+	e := newTestExporter(t, testExporterConfig(), &fakePool{conns: conns}, "guild", "server")
+
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_enabled Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason
+# TYPE wow_exporter_collector_enabled gauge
+wow_exporter_collector_enabled{collector="guild",realm="",reason=""} 1
+wow_exporter_collector_enabled{collector="server",realm="",reason=""} 1
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="guild"} 1
+wow_exporter_collector_success{collector="server"} 1
+# HELP wow_guild_count Number of guilds
+# TYPE wow_guild_count gauge
+wow_guild_count{realm="Azeroth"} 8
+# HELP wow_server_uptime_seconds Server uptime in seconds
+# TYPE wow_server_uptime_seconds gauge
+wow_server_uptime_seconds{realm="Azeroth"} 86400
+# HELP wow_database_up Whether a database is reachable (1) or not (0); realm is empty for the auth database
+# TYPE wow_database_up gauge
+wow_database_up{database="auth",realm=""} 1
+`, "wow_exporter_collector_enabled", "wow_exporter_collector_success", "wow_guild_count", "wow_server_uptime_seconds", "wow_database_up")
+}
+
+func TestExporterSkipsTimedOutCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectGuild(db)
+	expectServer(db, time.Minute)
+	cfg := testExporterConfig()
+	cfg.Scrape.CollectorTimeouts = map[string]time.Duration{"server": 10 * time.Millisecond}
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "guild", "server")
+
+	start := time.Now()
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="guild"} 1
+wow_exporter_collector_success{collector="server"} 0
+# HELP wow_exporter_scrape_errors_total Total number of collector runs that returned an error
+# TYPE wow_exporter_scrape_errors_total counter
+wow_exporter_scrape_errors_total{collector="server"} 1
+# HELP wow_guild_count Number of guilds
+# TYPE wow_guild_count gauge
+wow_guild_count{realm="Azeroth"} 8
+`, "wow_exporter_collector_success", "wow_exporter_scrape_errors_total", "wow_guild_count")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("scrape took %v, the timed out collector was waited for", elapsed)
+	}
+}
+
+func TestExporterSkipsCollectorOfUnavailableDatabase(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	pool := &fakePool{conns: conns, unavailable: fmt.Errorf("%w: auth", database.ErrUnavailable)}
+	e := newTestExporter(t, testExporterConfig(), pool, "server")
+
+	// An unavailable database is reported by wow_database_up, not as a scrape error
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_enabled Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason
+# TYPE wow_exporter_collector_enabled gauge
+wow_exporter_collector_enabled{collector="server",realm="",reason=""} 1
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="server"} 0
+`, "wow_exporter_collector_enabled", "wow_exporter_collector_success")
+	if n := testutil.CollectAndCount(e, "wow_exporter_scrape_errors_total", "wow_exporter_collector_last_success_timestamp_seconds"); n != 0 {
+		t.Errorf("got %d scrape error and last success series, want none", n)
+	}
+}
+
+func TestExporterDisablesCollectorWithMissingSchema(t *testing.T) {
+	conns, db := newTestConnections(t)
+	pool := &fakePool{conns: conns, missing: map[string]bool{"uptime": true}}
+	e := newTestExporter(t, testExporterConfig(), pool, "server")
+
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_enabled Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason
+# TYPE wow_exporter_collector_enabled gauge
+wow_exporter_collector_enabled{collector="server",realm="",reason="auth lacks table uptime"} 0
+`, "wow_exporter_collector_enabled")
+	if n := testutil.CollectAndCount(e, "wow_exporter_collector_success", "wow_exporter_collector_duration_seconds"); n != 0 {
+		t.Errorf("got %d success and duration series of a disabled collector, want none", n)
+	}
+
+	// The collector is enabled again once its table is back
+	pool.missing = nil
+	expectServer(db, 0)
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_enabled Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason
+# TYPE wow_exporter_collector_enabled gauge
+wow_exporter_collector_enabled{collector="server",realm="",reason=""} 1
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="server"} 1
+# HELP wow_server_uptime_seconds Server uptime in seconds
+# TYPE wow_server_uptime_seconds gauge
+wow_server_uptime_seconds{realm="Azeroth"} 86400
+`, "wow_exporter_collector_enabled", "wow_exporter_collector_success", "wow_server_uptime_seconds")
+}
+
+func TestExporterLeavesOutRealmWithMissingSchema(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectGuild(db)
+	northrend := &database.Realm{ID: 2, Name: "Northrend", Names: dbc.Default()}
+	northrend.Characters, _ = newFakeDatabase(t)
+	northrend.World, _ = newFakeDatabase(t)
+	conns.Realms = append(conns.Realms, northrend)
+	pool := &fakePool{conns: conns, gaps: []database.SchemaGap{
+		{RealmID: 2, Realm: "Northrend", Missing: "characters lacks table guild_eventlog"},
+	}}
+	e := newTestExporter(t, testExporterConfig(), pool, "guild")
+
+	// Northrend runs no query and Azeroth is still collected
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_enabled Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason
+# TYPE wow_exporter_collector_enabled gauge
+wow_exporter_collector_enabled{collector="guild",realm="",reason=""} 1
+wow_exporter_collector_enabled{collector="guild",realm="Northrend",reason="characters lacks table guild_eventlog"} 0
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="guild"} 1
+# HELP wow_guild_count Number of guilds
+# TYPE wow_guild_count gauge
+wow_guild_count{realm="Azeroth"} 8
+`, "wow_exporter_collector_enabled", "wow_exporter_collector_success", "wow_guild_count")
+}
+
+func TestExporterSkipsCollectorWhenOnlyRealmsLackingSchemaAreUp(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	pool := &fakePool{conns: conns, gaps: []database.SchemaGap{
+		{RealmID: 1, Realm: "Azeroth", Missing: "characters lacks table guild_eventlog"},
+	}}
+	e := newTestExporter(t, testExporterConfig(), pool, "guild")
+
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="guild"} 0
+`, "wow_exporter_collector_success")
+	if n := testutil.CollectAndCount(e, "wow_exporter_scrape_errors_total"); n != 0 {
+		t.Errorf("got %d scrape error series for an unavailable realm, want none", n)
+	}
+}
+
+func TestExporterLimitsSeries(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT houseid, COUNT(*) FROM auctionhouse GROUP BY houseid`).
+		WillReturnRows(sqlmock.NewRows([]string{"houseid", "COUNT(*)"}).
+			AddRow(1, 40).
+			AddRow(2, 55).
+			AddRow(7, 12))
+	cfg := testExporterConfig()
+	cfg.Cardinality.MaxSeries = 2
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "auction")
+
+	assertMetrics(t, e, `
+# HELP wow_auction_count Number of active auctions by house (faction)
+# TYPE wow_auction_count gauge
+wow_auction_count{house="Alliance",realm="Azeroth"} 40
+wow_auction_count{house="Horde",realm="Azeroth"} 55
+wow_auction_count{house="other",realm="Azeroth"} 12
+# HELP wow_exporter_series_dropped_total Total number of series over the series limit of their metric, folded into an "other" series when their values add up and dropped otherwise
+# TYPE wow_exporter_series_dropped_total counter
+wow_exporter_series_dropped_total{metric="wow_auction_count"} 1
+`, "wow_auction_count", "wow_exporter_series_dropped_total")
+}
+
+func TestNewExporterRejectsUnknownCollector(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	if _, err := newExporter(&fakePool{conns: conns}, testExporterConfig(), []string{"guilds"}); err == nil {
+		t.Error("got no error for an unknown collector")
+	}
+}
@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("economy", newEconomyCollector)
+}
+
+// copperPerGold converts characters.money, kept in copper, to gold
+const copperPerGold = 10000
+
+// wealthBuckets are the upper bounds in gold of the wealth histogram; a
+// character holds at most 214748 gold
+var wealthBuckets = []float64{1, 10, 100, 500, 1000, 5000, 10000, 50000, 100000, 200000}
+
+// wealthQuantiles are the quantiles of the wealth summary
+var wealthQuantiles = []float64{0.5, 0.9, 0.99}
+
+// economyCollector exports the gold held by characters. Bots and excluded
+// characters are left out, so only the economy of players is measured.
+type economyCollector struct {
+	goldTotal  *prometheus.Desc
+	wealth     *prometheus.Desc
+	quantiles  *prometheus.Desc
+	exclusions *exclusions
+}
+
+func newEconomyCollector(cfg *config.Config) Collector {
+	return &economyCollector{
+		goldTotal: newDesc(
+			"wow_economy_gold_total",
+			"Gold held by characters, by faction",
+			[]string{"realm", "faction"},
+		),
+		wealth: newDesc(
+			"wow_economy_character_wealth_gold",
+			"Distribution of the gold held per character",
+			[]string{"realm"},
+		),
+		quantiles: newDesc(
+			"wow_economy_character_wealth_quantile_gold",
+			"Quantiles of the gold held per character",
+			[]string{"realm"},
+		),
+		exclusions: newExclusions(cfg.Exclusions),
+	}
+}
+
+func (c *economyCollector) Name() string { return "economy" }
+func (c *economyCollector) Databases() []string {
+	return c.exclusions.databases(database.CharactersDatabase)
+}
+
+func (c *economyCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"guid", "account", "race", "money", "deleteDate"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *economyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.goldTotal
+	ch <- c.wealth
+	ch <- c.quantiles
+}
+
+func (c *economyCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *economyCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Every metric leaves out bots, so none is sent while bots cannot be
+	// told from players
+	bots, err := loadBots(ctx, realm)
+	if err != nil {
+		return err
+	}
+	included, args, err := c.exclusions.condition(ctx, conns.Auth, realm, "")
+	if err != nil {
+		return err
+	}
+	players := `(deleteDate IS NULL OR deleteDate = 0)
+		AND NOT ` + bots.condition("") + `
+		AND ` + included
+
+	// Gold by faction
+	query := `
+		SELECT race, SUM(money)
+		FROM characters
+		WHERE ` + players + `
+		GROUP BY race
+	`
+	rows, err := realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "gold_by_faction", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	goldTotal := newAccumulator()
+	for rows.Next() {
+		var race int
+		var copper float64
+		if err := rows.Scan(&race, &copper); err != nil {
+			return queryFailed(database.CharactersDatabase, "gold_by_faction", err)
+		}
+		if faction := realm.Names.Faction(race); faction != "" {
+			goldTotal.add(copper/copperPerGold, realm.Name, faction)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "gold_by_faction", err)
+	}
+	goldTotal.emit(ch, c.goldTotal)
+
+	// Wealth histogram, counting the characters at or below each bucket
+	columns := []string{"COUNT(*)", "COALESCE(SUM(money), 0)"}
+	bucketArgs := make([]any, len(wealthBuckets))
+	for i, bound := range wealthBuckets {
+		columns = append(columns, "COALESCE(SUM(money <= ?), 0)")
+		bucketArgs[i] = int64(bound * copperPerGold)
+	}
+	query = `
+		SELECT ` + strings.Join(columns, ", ") + `
+		FROM characters
+		WHERE ` + players + `
+	`
+	var count uint64
+	var copper float64
+	cumulative := make([]uint64, len(wealthBuckets))
+	dest := []any{&count, &copper}
+	for i := range cumulative {
+		dest = append(dest, &cumulative[i])
+	}
+	if err := realm.Characters.QueryRowContext(ctx, query, append(bucketArgs, args...)...).Scan(dest...); err != nil {
+		return queryFailed(database.CharactersDatabase, "wealth_distribution", err)
+	}
+	buckets := make(map[float64]uint64, len(wealthBuckets))
+	for i, bound := range wealthBuckets {
+		buckets[bound] = cumulative[i]
+	}
+	ch <- prometheus.MustNewConstHistogram(c.wealth, count, copper/copperPerGold, buckets, realm.Name)
+
+	// Wealth quantiles by the nearest-rank method. The quantiles are inlined
+	// so that MySQL computes the ranks in exact decimal arithmetic.
+	columns = columns[:0]
+	for _, q := range wealthQuantiles {
+		columns = append(columns, "MAX(CASE WHEN n = CEIL(total * "+strconv.FormatFloat(q, 'f', -1, 64)+") THEN money END)")
+	}
+	query = `
+		SELECT ` + strings.Join(columns, ", ") + `
+		FROM (
+			SELECT money, ROW_NUMBER() OVER (ORDER BY money) AS n, COUNT(*) OVER () AS total
+			FROM characters
+			WHERE ` + players + `
+		) ranked
+	`
+	values := make([]sql.NullInt64, len(wealthQuantiles))
+	dest = dest[:0]
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	if err := realm.Characters.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return queryFailed(database.CharactersDatabase, "wealth_quantiles", err)
+	}
+	quantiles := make(map[float64]float64, len(wealthQuantiles))
+	for i, q := range wealthQuantiles {
+		if values[i].Valid {
+			quantiles[q] = float64(values[i].Int64) / copperPerGold
+		}
+	}
+	ch <- prometheus.MustNewConstSummary(c.quantiles, count, copper/copperPerGold, quantiles, realm.Name)
+
+	return nil
+}
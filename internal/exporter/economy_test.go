@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func TestEconomyCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.addPlayerbots(t, conns)
+	db.playerbots.ExpectQuery(`SELECT DISTINCT bot FROM playerbots_random_bots`).
+		WillReturnRows(sqlmock.NewRows([]string{"bot"}).AddRow(101))
+	db.playerbots.ExpectQuery(`SELECT account_id FROM playerbots_account_type WHERE account_type <> 0`).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id"}))
+
+	players := `WHERE (deleteDate IS NULL OR deleteDate = 0) AND NOT (guid IN (101)) AND 1`
+	db.characters.ExpectQuery(`SELECT race, SUM(money) FROM characters ` + players + ` GROUP BY race`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "SUM(money)"}).
+			AddRow(1, "1500000").
+			AddRow(3, "250000").
+			AddRow(2, "90000000"))
+	db.characters.ExpectQuery(`SELECT COUNT(*), COALESCE(SUM(money), 0), COALESCE(SUM(money <= ?), 0)`).
+		WithArgs(10000, 100000, 1000000, 5000000, 10000000, 50000000, 100000000, 500000000, 1000000000, 2000000000).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)", "SUM(money)", "b1", "b2", "b3", "b4", "b5", "b6", "b7", "b8", "b9", "b10"}).
+			AddRow(10, "91750000", "2", "4", "6", "8", "9", "9", "9", "10", "10", "10"))
+	db.characters.ExpectQuery(`SELECT MAX(CASE WHEN n = CEIL(total * 0.5) THEN money END), MAX(CASE WHEN n = CEIL(total * 0.9) THEN money END), MAX(CASE WHEN n = CEIL(total * 0.99) THEN money END) FROM ( SELECT money, ROW_NUMBER() OVER (ORDER BY money) AS n, COUNT(*) OVER () AS total FROM characters ` + players + ` ) ranked`).
+		WillReturnRows(sqlmock.NewRows([]string{"p50", "p90", "p99"}).AddRow(250000, 8000000, 90000000))
+
+	assertGolden(t, "economy", conns, "economy")
+}
+
+func TestEconomyCollectorNoCharacters(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT race, SUM(money) FROM characters WHERE (deleteDate IS NULL OR deleteDate = 0) AND NOT 0 AND 1 GROUP BY race`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "SUM(money)"}))
+	db.characters.ExpectQuery(`SELECT COUNT(*), COALESCE(SUM(money), 0)`).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)", "SUM(money)", "b1", "b2", "b3", "b4", "b5", "b6", "b7", "b8", "b9", "b10"}).
+			AddRow(0, "0", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0"))
+	db.characters.ExpectQuery(`FROM characters WHERE (deleteDate IS NULL OR deleteDate = 0) AND NOT 0 AND 1 ) ranked`).
+		WillReturnRows(sqlmock.NewRows([]string{"p50", "p90", "p99"}).AddRow(nil, nil, nil))
+
+	assertGolden(t, "economy", conns, "economy_empty")
+}
+
+func TestEconomyCollectorPlayerbotsDown(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	conns.Realms[0].PlayerbotsDown = true
+
+	if n, err := updateMetrics("economy", conns); n != 0 || !errors.Is(err, database.ErrUnavailable) {
+		t.Errorf("got %d metrics and error %v, want none and ErrUnavailable", n, err)
+	}
+}
@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+// erNoSuchTable is the MySQL error of a query reading a table that does not
+// exist
+const erNoSuchTable = 1146
+
+// errExporterClosed is returned when a target is probed while the exporter
+// is closing
+var errExporterClosed = errors.New("exporter is closed")
+
+// queryError is a failed query of a collector. It carries the database and
+// query name so the failure can be logged with structured fields.
+type queryError struct {
+	database string
+	query    string
+	err      error
+}
+
+// queryFailed wraps the error of the named query on database
+func queryFailed(database, query string, err error) error {
+	return &queryError{database: database, query: query, err: err}
+}
+
+func (e *queryError) Error() string {
+	return fmt.Sprintf("query %s on %s database: %v", e.query, e.database, e.err)
+}
+
+func (e *queryError) Unwrap() error { return e.err }
+
+// mysqlErrorNumber returns the number of the MySQL server error err wraps,
+// or 0 when err is not a server error
+func mysqlErrorNumber(err error) uint16 {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me.Number
+	}
+	return 0
+}
+
+// realmError is a failure of a collector on one realm
+type realmError struct {
+	realm string
+	err   error
+}
+
+func (e *realmError) Error() string {
+	return fmt.Sprintf("realm %s: %v", e.realm, e.err)
+}
+
+func (e *realmError) Unwrap() error { return e.err }
+
+// unavailable reports whether every error joined in err is
+// database.ErrUnavailable, so that a realm whose database is down does not
+// hide the failures of the others
+func unavailable(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			if !unavailable(err) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, database.ErrUnavailable)
+}
+
+// logCollectorError logs every error joined in err separately, with the
+// realm, database and query it occurred in when known. Unavailable
+// databases are logged at debug level.
+func logCollectorError(collector string, duration time.Duration, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			logCollectorError(collector, duration, err)
+		}
+		return
+	}
+
+	attrs := []any{"collector", collector, "duration", duration}
+	if errors.Is(err, database.ErrUnavailable) {
+		slog.Debug("Skipping collector", append(attrs, "err", err)...)
+		return
+	}
+	var re *realmError
+	if errors.As(err, &re) {
+		attrs = append(attrs, "realm", re.realm)
+		err = re.err
+	}
+	var qe *queryError
+	if errors.As(err, &qe) {
+		attrs = append(attrs, "database", qe.database, "query", qe.query)
+		err = qe.err
+	}
+	slog.Error("Error collecting metrics", append(attrs, "err", err)...)
+}
@@ -0,0 +1,258 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
+
+// errConnectionReset is the error the fake databases fail queries with
+var errConnectionReset = errors.New("connection reset")
+
+// updateMetrics runs the named collector once against conns and returns the
+// number of metrics it sent along with its error
+func updateMetrics(name string, conns *database.Connections) (int, error) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range ch {
+			n++
+		}
+		done <- n
+	}()
+	err := collectorFactories[name](testConfig()).Update(context.Background(), conns, ch)
+	close(ch)
+	return <-done, err
+}
+
+func TestCollectorQueryErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		collector string
+		// playerbots gives the realm a playerbots database
+		playerbots bool
+		expect     func(db *fakeDatabases)
+		database   string
+		query      string
+		// realm is the realm the error is reported for, empty for queries
+		// of the auth database shared by all realms
+		realm string
+	}{
+		{
+			name:      "single row of auth",
+			collector: "accounts",
+			expect: func(db *fakeDatabases) {
+				db.auth.ExpectQuery(`SELECT COUNT(*) FROM account`).WillReturnError(errConnectionReset)
+			},
+			database: database.AuthDatabase,
+			query:    "total_accounts",
+		},
+		{
+			name:      "single row of a realm",
+			collector: "guild",
+			expect: func(db *fakeDatabases) {
+				db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild`).WillReturnError(errConnectionReset)
+			},
+			database: database.CharactersDatabase,
+			query:    "guild_count",
+			realm:    "Azeroth",
+		},
+		{
+			name:      "rows",
+			collector: "auction",
+			expect: func(db *fakeDatabases) {
+				db.characters.ExpectQuery(`FROM auctionhouse`).WillReturnError(errConnectionReset)
+			},
+			database: database.CharactersDatabase,
+			query:    "auctions_by_house",
+			realm:    "Azeroth",
+		},
+		{
+			name:      "row scan",
+			collector: "auction",
+			expect: func(db *fakeDatabases) {
+				db.characters.ExpectQuery(`FROM auctionhouse`).
+					WillReturnRows(sqlmock.NewRows([]string{"houseid", "COUNT(*)"}).AddRow("alliance", 40))
+			},
+			database: database.CharactersDatabase,
+			query:    "auctions_by_house",
+			realm:    "Azeroth",
+		},
+		{
+			name:      "row scan of a battleground",
+			collector: "battleground",
+			expect: func(db *fakeDatabases) {
+				db.characters.ExpectQuery(`SELECT COUNT(*) FROM battleground_deserters`).WillReturnRows(count(3))
+				db.characters.ExpectQuery(`FROM battleground_deserters GROUP BY type`).
+					WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).AddRow("deserter", 2))
+			},
+			database: database.CharactersDatabase,
+			query:    "battleground_deserters_by_type",
+			realm:    "Azeroth",
+		},
+		{
+			name:      "rows interrupted",
+			collector: "auction",
+			expect: func(db *fakeDatabases) {
+				db.characters.ExpectQuery(`FROM auctionhouse`).
+					WillReturnRows(sqlmock.NewRows([]string{"houseid", "COUNT(*)"}).
+						AddRow(1, 40).
+						AddRow(2, 55).
+						RowError(1, errConnectionReset))
+			},
+			database: database.CharactersDatabase,
+			query:    "auctions_by_house",
+			realm:    "Azeroth",
+		},
+		{
+			name:      "single row interrupted",
+			collector: "battleground",
+			expect: func(db *fakeDatabases) {
+				db.characters.ExpectQuery(`SELECT COUNT(*) FROM battleground_deserters`).WillReturnRows(count(3))
+				db.characters.ExpectQuery(`FROM battleground_deserters GROUP BY type`).
+					WillReturnRows(sqlmock.NewRows([]string{"type", "count"}))
+				db.characters.ExpectQuery(`SELECT COUNT(*) FROM character_battleground_random`).WillReturnRows(count(6))
+				db.characters.ExpectQuery(`LEFT JOIN pvpstats_players bp ON bg.id = bp.battleground_id`).
+					WillReturnRows(sqlmock.NewRows([]string{"total_battlegrounds", "total_players"}).AddRow(12, 150))
+				db.characters.ExpectQuery(`FROM pvpstats_battlegrounds GROUP BY type`).
+					WillReturnRows(sqlmock.NewRows([]string{"type", "count"}))
+				db.characters.ExpectQuery(`GROUP BY bracket_id`).
+					WillReturnRows(sqlmock.NewRows([]string{"bracket_id", "count"}))
+				db.characters.ExpectQuery(`WHERE winner_faction IN (0, 1)`).
+					WillReturnRows(sqlmock.NewRows([]string{"winner_faction", "count"}))
+				db.characters.ExpectQuery(`FROM pvpstats_players`).
+					WillReturnRows(sqlmock.NewRows([]string{"total_participants", "total_winners", "avg_killing_blows", "avg_deaths", "avg_honorable_kills", "avg_bonus_honor", "avg_damage_done", "avg_healing_done"}).
+						AddRow(150, 75, 2.5, 3.0, 12.0, 150.0, 40000.0, nil).
+						RowError(0, errConnectionReset))
+			},
+			database: database.CharactersDatabase,
+			query:    "battleground_player_stats",
+			realm:    "Azeroth",
+		},
+		{
+			name:       "optional playerbots database",
+			collector:  "playerbots",
+			playerbots: true,
+			expect: func(db *fakeDatabases) {
+				db.playerbots.ExpectQuery(`FROM playerbots_random_bots`).WillReturnError(errConnectionReset)
+			},
+			database: database.PlayerbotsDatabase,
+			query:    "random_bots_by_event",
+			realm:    "Azeroth",
+		},
+		{
+			name:       "bots of the players",
+			collector:  "players",
+			playerbots: true,
+			expect: func(db *fakeDatabases) {
+				db.playerbots.ExpectQuery(`SELECT DISTINCT bot FROM playerbots_random_bots`).WillReturnError(errConnectionReset)
+			},
+			database: database.PlayerbotsDatabase,
+			query:    "random_bots",
+			realm:    "Azeroth",
+		},
+		{
+			name:       "bot accounts of the economy",
+			collector:  "economy",
+			playerbots: true,
+			expect: func(db *fakeDatabases) {
+				db.playerbots.ExpectQuery(`SELECT DISTINCT bot FROM playerbots_random_bots`).
+					WillReturnRows(sqlmock.NewRows([]string{"bot"}).AddRow(101))
+				db.playerbots.ExpectQuery(`FROM playerbots_account_type`).
+					WillReturnError(&mysql.MySQLError{Number: 1040, Message: "Too many connections"})
+			},
+			database: database.PlayerbotsDatabase,
+			query:    "bot_accounts",
+			realm:    "Azeroth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns, db := newTestConnections(t)
+			if tt.playerbots {
+				db.addPlayerbots(t, conns)
+			}
+			tt.expect(db)
+			_, err := updateMetrics(tt.collector, conns)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+
+			var qe *queryError
+			if !errors.As(err, &qe) {
+				t.Fatalf("got error %v, want a query error", err)
+			}
+			if qe.database != tt.database || qe.query != tt.query {
+				t.Errorf("got query %s on %s, want %s on %s", qe.query, qe.database, tt.query, tt.database)
+			}
+			var re *realmError
+			switch {
+			case !errors.As(err, &re) && tt.realm != "":
+				t.Errorf("got error %v, want one of realm %s", err, tt.realm)
+			case re != nil && re.realm != tt.realm:
+				t.Errorf("got error of realm %q, want %q", re.realm, tt.realm)
+			}
+		})
+	}
+}
+
+func TestCollectorContinuesAfterFailedRealm(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild`).WillReturnError(errConnectionReset)
+	northrend := &database.Realm{ID: 2, Name: "Northrend", Names: dbc.Default()}
+	var characters sqlmock.Sqlmock
+	northrend.Characters, characters = newFakeDatabase(t)
+	northrend.World, _ = newFakeDatabase(t)
+	conns.Realms = append(conns.Realms, northrend)
+	characters.ExpectQuery(`SELECT COUNT(*) FROM guild`).WillReturnRows(count(3))
+	characters.ExpectQuery(`SELECT COUNT(*) FROM guild_eventlog`).WillReturnRows(count(12))
+
+	n, err := updateMetrics("guild", conns)
+	var re *realmError
+	if !errors.As(err, &re) || re.realm != "Azeroth" {
+		t.Errorf("got error %v, want one of realm Azeroth", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d metrics, want the 2 metrics of Northrend", n)
+	}
+}
+
+func TestQueryErrorMessage(t *testing.T) {
+	err := &realmError{realm: "Azeroth", err: queryFailed(database.CharactersDatabase, "guild_count", errConnectionReset)}
+	if got, want := err.Error(), "realm Azeroth: query guild_count on characters database: connection reset"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !errors.Is(err, errConnectionReset) {
+		t.Error("the error does not wrap the error of the query")
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	down := &realmError{realm: "Azeroth", err: database.ErrUnavailable}
+	failed := &realmError{realm: "Northrend", err: queryFailed(database.CharactersDatabase, "guild_count", errConnectionReset)}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unavailable", err: down, want: true},
+		{name: "every realm unavailable", err: errors.Join(down, down), want: true},
+		{name: "one realm failed", err: errors.Join(down, failed), want: false},
+		{name: "failed", err: failed, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unavailable(tt.err); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
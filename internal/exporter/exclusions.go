@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+// patternCheckTimeout bounds the check of the name patterns at startup
+const patternCheckTimeout = 30 * time.Second
+
+// exclusions applies the configured exclusion rules to the characters
+// counted by character-based collectors
+type exclusions struct {
+	cfg config.ExclusionConfig
+}
+
+func newExclusions(cfg config.ExclusionConfig) *exclusions {
+	return &exclusions{cfg: cfg}
+}
+
+// databases returns the databases of a collector applying the rules, adding
+// the auth database when game master accounts are excluded
+func (e *exclusions) databases(databases ...string) []string {
+	if e.cfg.MinGMLevel > 0 && !slices.Contains(databases, database.AuthDatabase) {
+		databases = append(databases, database.AuthDatabase)
+	}
+	return databases
+}
+
+// requirements returns the tables and columns the rules read
+func (e *exclusions) requirements() []database.Requirement {
+	var columns []string
+	if len(e.cfg.NamePatterns) > 0 {
+		columns = append(columns, "name")
+	}
+	if len(e.cfg.Accounts) > 0 || e.cfg.MinGMLevel > 0 {
+		columns = append(columns, "account")
+	}
+	if e.cfg.MinAge > 0 {
+		columns = append(columns, "creation_date")
+	}
+
+	var reqs []database.Requirement
+	if len(columns) > 0 {
+		reqs = append(reqs, database.Requirement{Database: database.CharactersDatabase, Table: "characters", Columns: columns})
+	}
+	if e.cfg.MinGMLevel > 0 {
+		reqs = append(reqs, database.Requirement{Database: database.AuthDatabase, Table: "account_access", Columns: []string{"id", "gmlevel", "RealmID"}})
+	}
+	return reqs
+}
+
+// condition returns an SQL condition over the characters table, whose
+// columns are qualified by prefix (e.g. "c."), that holds for the characters
+// of realm the rules keep, along with its arguments. Game master accounts are
+// read from auth.
+func (e *exclusions) condition(ctx context.Context, auth database.Querier, realm *database.Realm, prefix string) (string, []any, error) {
+	var terms []string
+	var args []any
+	for _, pattern := range e.cfg.NamePatterns {
+		terms = append(terms, prefix+"name NOT REGEXP ?")
+		args = append(args, pattern)
+	}
+
+	accounts := make([]int64, 0, len(e.cfg.Accounts))
+	for _, id := range e.cfg.Accounts {
+		accounts = append(accounts, int64(id))
+	}
+	if e.cfg.MinGMLevel > 0 {
+		query := `SELECT DISTINCT id FROM account_access WHERE gmlevel >= ? AND RealmID IN (-1, ?)`
+		gms, err := queryIDs(ctx, auth, query, e.cfg.MinGMLevel, realm.ID)
+		if err != nil {
+			return "", nil, queryFailed(database.AuthDatabase, "gm_accounts", err)
+		}
+		accounts = append(accounts, gms...)
+	}
+	if len(accounts) > 0 {
+		slices.Sort(accounts)
+		terms = append(terms, prefix+"account NOT IN ("+joinIDs(slices.Compact(accounts))+")")
+	}
+
+	if e.cfg.MinAge > 0 {
+		terms = append(terms, "("+prefix+"creation_date IS NULL OR "+prefix+"creation_date < DATE_SUB(NOW(), INTERVAL ? SECOND))")
+		args = append(args, int64(e.cfg.MinAge.Seconds()))
+	}
+
+	if len(terms) == 0 {
+		return "1", nil, nil
+	}
+	return "(" + strings.Join(terms, " AND ") + ")", args, nil
+}
+
+// checkPatterns has the characters database of every realm of conns compile
+// the name patterns. The patterns are evaluated by MySQL's REGEXP, whose
+// dialect depends on the server, so only the server can tell whether they
+// are valid. Only patterns the server rejects fail the check; other errors,
+// such as timeouts, are logged, as the databases may misbehave at any time.
+func (e *exclusions) checkPatterns(ctx context.Context, conns *database.Connections) error {
+	var errs []error
+	for _, realm := range conns.Realms {
+		for i, pattern := range e.cfg.NamePatterns {
+			var matched sql.NullBool
+			err := realm.Characters.QueryRowContext(ctx, `SELECT '' REGEXP ?`, pattern).Scan(&matched)
+			switch {
+			case err == nil:
+			case isRegexpError(err):
+				errs = append(errs, fmt.Errorf("exclusions.name_patterns[%d] on realm %s: %w", i, realm.Name, err))
+			default:
+				slog.Warn("Could not check name pattern", "realm", realm.Name, "pattern", pattern, "err", err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isRegexpError reports whether err is MySQL rejecting a regular
+// expression: error 1139 of MariaDB and MySQL before 8.0, or one of the
+// errors 3685 to 3699 of the regular expression library of MySQL 8.0
+func isRegexpError(err error) bool {
+	n := mysqlErrorNumber(err)
+	return n == 1139 || n >= 3685 && n <= 3699
+}
+
+// checkNamePatterns checks the name patterns of cfg against the characters
+// databases of pool. Realms whose characters database is down are not
+// checked; their queries fail once it is back if a pattern is invalid.
+func checkNamePatterns(pool databasePool, cfg config.ExclusionConfig) error {
+	if len(cfg.NamePatterns) == 0 {
+		return nil
+	}
+	conns, err := pool.Connections(database.CharactersDatabase)
+	if errors.Is(err, database.ErrUnavailable) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), patternCheckTimeout)
+	defer cancel()
+	return newExclusions(cfg).checkPatterns(ctx, conns)
+}
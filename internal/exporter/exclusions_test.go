@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+func TestExclusionsCheckPatterns(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT '' REGEXP ?`).WithArgs("^Test").
+		WillReturnRows(sqlmock.NewRows([]string{"match"}).AddRow(0))
+	db.characters.ExpectQuery(`SELECT '' REGEXP ?`).WithArgs("[Gm").
+		WillReturnError(&mysql.MySQLError{Number: 3685, Message: "Illegal argument to a regular expression."})
+
+	e := newExclusions(config.ExclusionConfig{NamePatterns: []string{"^Test", "[Gm"}})
+	err := e.checkPatterns(context.Background(), conns)
+	if err == nil {
+		t.Fatal("expected the rejected pattern to fail the check")
+	}
+	if want := "exclusions.name_patterns[1] on realm Azeroth"; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to mention %q", err, want)
+	}
+}
+
+func TestExclusionsCheckPatternsIgnoresDatabaseErrors(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT '' REGEXP ?`).WithArgs("^Test").WillReturnError(errConnectionReset)
+	db.characters.ExpectQuery(`SELECT '' REGEXP ?`).WithArgs("[Gm").
+		WillReturnError(&mysql.MySQLError{Number: 1142, Message: "SELECT command denied to user"})
+
+	e := newExclusions(config.ExclusionConfig{NamePatterns: []string{"^Test", "[Gm"}})
+	if err := e.checkPatterns(context.Background(), conns); err != nil {
+		t.Errorf("got error %v, want failures other than rejected patterns to be ignored", err)
+	}
+}
+
+func TestNewExporterStartsWhenPatternCheckFails(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT '' REGEXP ?`).WithArgs("^Test").WillReturnError(errConnectionReset)
+	cfg := testExporterConfig()
+	cfg.Exclusions.NamePatterns = []string{"^Test"}
+
+	if _, err := newExporter(&fakePool{conns: conns}, cfg, []string{"players"}); err != nil {
+		t.Errorf("got error %v, want the exporter to start", err)
+	}
+}
@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("guild", newGuildCollector)
+}
+
+// guildCollector exports guild metrics
+type guildCollector struct {
+	count  *prometheus.Desc
+	events *prometheus.Desc
+}
+
+func newGuildCollector(cfg *config.Config) Collector {
+	return &guildCollector{
+		count: newDesc(
+			"wow_guild_count",
+			"Number of guilds",
+			[]string{"realm"},
+		),
+		events: newDesc(
+			"wow_guild_events",
+			"Number of guild events",
+			[]string{"realm"},
+		),
+	}
+}
+
+func (c *guildCollector) Name() string        { return "guild" }
+func (c *guildCollector) Databases() []string { return []string{database.CharactersDatabase} }
+
+func (c *guildCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.CharactersDatabase, Table: "guild"},
+		{Database: database.CharactersDatabase, Table: "guild_eventlog"},
+	}
+}
+
+func (c *guildCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.count
+	ch <- c.events
+}
+
+func (c *guildCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, realm, ch)
+	})
+}
+
+func (c *guildCollector) updateRealm(ctx context.Context, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	var count int
+	query := `SELECT COUNT(*) FROM guild`
+	if err := realm.Characters.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return queryFailed(database.CharactersDatabase, "guild_count", err)
+	}
+	gauge(ch, c.count, float64(count), realm.Name)
+
+	query = `SELECT COUNT(*) FROM guild_eventlog`
+	if err := realm.Characters.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return queryFailed(database.CharactersDatabase, "guild_events", err)
+	}
+	gauge(ch, c.events, float64(count), realm.Name)
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package exporter
+
+import "testing"
+
+func TestGuildCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild`).WillReturnRows(count(8))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild_eventlog`).WillReturnRows(count(230))
+
+	assertGolden(t, "guild", conns, "guild")
+}
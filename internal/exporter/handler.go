@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeTimeoutHeader is set by Prometheus to the scrape timeout in seconds
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// scrape binds an Exporter to the context and collectors of a single HTTP scrape
+type scrape struct {
+	exporter   *Exporter
+	ctx        context.Context
+	collectors []Collector
+}
+
+// Describe implements prometheus.Collector
+func (s *scrape) Describe(ch chan<- *prometheus.Desc) {
+	s.exporter.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (s *scrape) Collect(ch chan<- prometheus.Metric) {
+	s.exporter.collect(s.ctx, s.collectors, ch)
+}
+
+// Handler returns an http.Handler serving the exporter metrics alongside the
+// default registry. Every scrape is bounded by the deadline Prometheus
+// announces in the X-Prometheus-Scrape-Timeout-Seconds header, and may be
+// limited to a subset of collectors with collect[] query parameters.
+func (e *Exporter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		collectors := e.collectors
+		if names := r.URL.Query()["collect[]"]; len(names) > 0 {
+			var err error
+			if collectors, err = e.filterCollectors(names); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := scrapeContext(r, e.scrape.TimeoutOffset)
+		defer cancel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(&scrape{exporter: e, ctx: ctx, collectors: collectors})
+
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// ReadyHandler returns an http.Handler reporting whether every configured
+// database answers a ping within timeout. Databases of /probe targets are
+// not checked.
+func (e *Exporter) ReadyHandler(timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		if err := e.pool.Ping(ctx); err != nil {
+			http.Error(w, "Not ready: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		WriteWithLog(w, []byte("Ready\n"))
+	})
+}
+
+// scrapeContext derives the context for a scrape from the request and the
+// Prometheus scrape timeout header, minus offset
+func scrapeContext(r *http.Request, offset time.Duration) (context.Context, context.CancelFunc) {
+	if value := r.Header.Get(scrapeTimeoutHeader); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err == nil && seconds > 0 {
+			timeout := time.Duration(seconds*float64(time.Second)) - offset
+			if timeout > 0 {
+				return context.WithTimeout(r.Context(), timeout)
+			}
+		}
+	}
+	return context.WithCancel(r.Context())
+}
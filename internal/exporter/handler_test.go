@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// get serves a GET request of target with h and returns the response
+func get(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerFiltersCollectors(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectGuild(db)
+	e := newTestExporter(t, testExporterConfig(), &fakePool{conns: conns}, "guild", "server")
+
+	w := get(e.Handler(), "/metrics?collect[]=guild", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `wow_guild_count{realm="Azeroth"} 8`) {
+		t.Errorf("guild metrics are missing:\n%s", body)
+	}
+	if strings.Contains(body, `collector="server"`) {
+		t.Errorf("the server collector ran although it was not selected:\n%s", body)
+	}
+}
+
+func TestHandlerRejectsUnknownCollector(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	e := newTestExporter(t, testExporterConfig(), &fakePool{conns: conns}, "guild")
+
+	w := get(e.Handler(), "/metrics?collect[]=server", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), `collector "server" is not enabled`) {
+		t.Errorf("unexpected body %q", w.Body)
+	}
+}
+
+func TestHandlerHonoursScrapeTimeout(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectServer(db, time.Minute)
+	cfg := testExporterConfig()
+	cfg.Scrape.Timeout = time.Minute
+	cfg.Scrape.TimeoutOffset = 500 * time.Millisecond
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "server")
+
+	start := time.Now()
+	w := get(e.Handler(), "/metrics", http.Header{scrapeTimeoutHeader: {"0.6"}})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("scrape took %v, its deadline was ignored", elapsed)
+	}
+	if !strings.Contains(w.Body.String(), `wow_exporter_collector_success{collector="server"} 0`) {
+		t.Errorf("the timed out collector is not reported as failed:\n%s", w.Body)
+	}
+}
+
+func TestScrapeContext(t *testing.T) {
+	const offset = 500 * time.Millisecond
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{name: "no header"},
+		{name: "timeout", header: "10", want: 10*time.Second - offset},
+		{name: "fractional timeout", header: "2.5", want: 2 * time.Second},
+		{name: "timeout within offset", header: "0.4"},
+		{name: "zero", header: "0"},
+		{name: "invalid", header: "soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				r.Header.Set(scrapeTimeoutHeader, tt.header)
+			}
+			ctx, cancel := scrapeContext(r, offset)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if tt.want == 0 {
+				if ok {
+					t.Errorf("got deadline in %v, want none", time.Until(deadline))
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("got no deadline")
+			}
+			if got := time.Until(deadline); got > tt.want || got < tt.want-time.Second {
+				t.Errorf("got deadline in %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	pool := &fakePool{conns: conns}
+	e := newTestExporter(t, testExporterConfig(), pool, "guild")
+
+	if w := get(e.ReadyHandler(time.Second), "/-/ready", nil); w.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	pool.pingErr = errors.New("realm 1 characters: connection refused")
+	w := get(e.ReadyHandler(time.Second), "/-/ready", nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(w.Body.String(), "connection refused") {
+		t.Errorf("unexpected body %q", w.Body)
+	}
+}
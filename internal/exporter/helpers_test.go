@@ -0,0 +1,246 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the collected metrics")
+
+// fakeDatabases holds the fakes behind the connections of a test. Queries
+// are expected in the order they are declared on each database.
+type fakeDatabases struct {
+	auth       sqlmock.Sqlmock
+	characters sqlmock.Sqlmock
+	world      sqlmock.Sqlmock
+	playerbots sqlmock.Sqlmock
+}
+
+// queryMatcher matches queries containing the expected text, ignoring
+// differences in white space
+var queryMatcher = sqlmock.QueryMatcherFunc(func(expected, actual string) error {
+	if !strings.Contains(strings.Join(strings.Fields(actual), " "), strings.Join(strings.Fields(expected), " ")) {
+		return fmt.Errorf("query %q does not contain %q", actual, expected)
+	}
+	return nil
+})
+
+// newFakeDatabase returns a fake database that fails the test if one of its
+// expected queries did not run
+func newFakeDatabase(t *testing.T) (database.Querier, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(queryMatcher))
+	if err != nil {
+		t.Fatalf("creating fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+// newTestConnections returns connections to an auth database and to a
+// single realm with ID 1 named Azeroth, backed by fakes
+func newTestConnections(t *testing.T) (*database.Connections, *fakeDatabases) {
+	t.Helper()
+	fakes := &fakeDatabases{}
+	conns := &database.Connections{}
+	conns.Auth, fakes.auth = newFakeDatabase(t)
+	realm := &database.Realm{ID: 1, Name: "Azeroth", Names: dbc.Default()}
+	realm.Characters, fakes.characters = newFakeDatabase(t)
+	realm.World, fakes.world = newFakeDatabase(t)
+	conns.Realms = []*database.Realm{realm}
+	return conns, fakes
+}
+
+// addPlayerbots gives the realm of conns a fake playerbots database
+func (f *fakeDatabases) addPlayerbots(t *testing.T, conns *database.Connections) {
+	t.Helper()
+	conns.Realms[0].Playerbots, f.playerbots = newFakeDatabase(t)
+}
+
+// testConfig returns the configuration collectors are created with in tests
+func testConfig() *config.Config {
+	return &config.Config{
+		Redaction: config.RedactionConfig{Character: "off", Account: "off", IP: "off"},
+	}
+}
+
+// fakePool is a databasePool serving fixed connections, standing in for
+// database.Pool in exporter tests
+type fakePool struct {
+	conns *database.Connections
+	// missing holds the tables whose requirements CheckSchema fails
+	missing map[string]bool
+	// gaps holds the realms CheckSchema reports as lacking a requirement
+	gaps []database.SchemaGap
+	// unavailable is returned by Connections when set
+	unavailable error
+	// pingErr is returned by Ping
+	pingErr error
+}
+
+// CheckSchema fails on the first requirement of a missing table, and
+// otherwise returns the fixed gaps
+func (p *fakePool) CheckSchema(reqs []database.Requirement) ([]database.SchemaGap, error) {
+	for _, req := range reqs {
+		if p.missing[req.Table] {
+			return nil, fmt.Errorf("%s lacks table %s", req.Database, req.Table)
+		}
+	}
+	return p.gaps, nil
+}
+
+// Connections returns the fixed connections, or the unavailable error
+func (p *fakePool) Connections(...string) (*database.Connections, error) {
+	if p.unavailable != nil {
+		return nil, p.unavailable
+	}
+	return p.conns, nil
+}
+
+// Statuses reports an available auth database
+func (p *fakePool) Statuses() []database.Status {
+	return []database.Status{{Database: database.AuthDatabase, Up: true}}
+}
+
+func (p *fakePool) Ping(context.Context) error { return p.pingErr }
+func (p *fakePool) Close()                     {}
+
+// testExporterConfig returns the configuration exporters are created with
+// in tests, running every collector on scrape with a generous timeout
+func testExporterConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Scrape = config.ScrapeConfig{
+		Timeout:  5 * time.Second,
+		Interval: time.Hour,
+	}
+	return cfg
+}
+
+// newTestExporter returns an exporter running the named collectors against
+// pool
+func newTestExporter(t *testing.T, cfg *config.Config, pool *fakePool, names ...string) *Exporter {
+	t.Helper()
+	e, err := newExporter(pool, cfg, names)
+	if err != nil {
+		t.Fatalf("creating exporter: %v", err)
+	}
+	return e
+}
+
+// assertMetrics compares the named metrics collected from c with expected,
+// given in the text exposition format
+func assertMetrics(t *testing.T, c prometheus.Collector, expected string, names ...string) {
+	t.Helper()
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+}
+
+// testCollector runs a collector against fixed connections as a
+// prometheus.Collector, failing the test when the collector fails
+type testCollector struct {
+	t         *testing.T
+	collector Collector
+	conns     *database.Connections
+}
+
+// Describe implements prometheus.Collector
+func (c *testCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *testCollector) Collect(ch chan<- prometheus.Metric) {
+	if err := c.collector.Update(context.Background(), c.conns, ch); err != nil {
+		c.t.Errorf("%s collector failed: %v", c.collector.Name(), err)
+	}
+}
+
+// assertGolden runs the named collector against conns and compares its
+// metrics with testdata/<golden>.prom. With -update the file is rewritten
+// instead.
+func assertGolden(t *testing.T, name string, conns *database.Connections, golden string) {
+	t.Helper()
+	assertGoldenWithConfig(t, testConfig(), name, conns, golden)
+}
+
+// assertGoldenWithConfig is assertGolden for a collector created with cfg
+func assertGoldenWithConfig(t *testing.T, cfg *config.Config, name string, conns *database.Connections, golden string) {
+	t.Helper()
+	assertCollectorGolden(t, collectorFactories[name](cfg), conns, golden)
+}
+
+// assertCollectorGolden is assertGolden for an existing collector, such as
+// one that keeps state between runs
+func assertCollectorGolden(t *testing.T, collector Collector, conns *database.Connections, golden string) {
+	t.Helper()
+	c := &testCollector{t: t, collector: collector, conns: conns}
+	path := filepath.Join("testdata", golden+".prom")
+
+	if *update {
+		writeGolden(t, c, path)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	defer f.Close()
+	if err := testutil.CollectAndCompare(c, f); err != nil {
+		t.Error(err)
+	}
+}
+
+// runCollector runs collector once against conns, discarding its metrics
+func runCollector(t *testing.T, collector Collector, conns *database.Connections) {
+	t.Helper()
+	testutil.CollectAndCount(&testCollector{t: t, collector: collector, conns: conns})
+}
+
+// writeGolden writes the metrics of c to path in the text exposition format
+func writeGolden(t *testing.T, c prometheus.Collector, path string) {
+	t.Helper()
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+
+	var out bytes.Buffer
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(&out, family); err != nil {
+			t.Fatalf("formatting metrics: %v", err)
+		}
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatalf("writing golden file: %v", err)
+	}
+}
+
+// count returns the fake result of a single COUNT(*) query
+func count(n int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(n)
+}
@@ -0,0 +1,229 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/constants"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("instance", newInstanceCollector)
+}
+
+// instanceCollector exports dungeon, raid and LFG metrics
+type instanceCollector struct {
+	active                *prometheus.Desc
+	byDifficulty          *prometheus.Desc
+	completedEncounters   *prometheus.Desc
+	resets                *prometheus.Desc
+	charactersInInstances *prometheus.Desc
+	lfgData               *prometheus.Desc
+	lagReports            *prometheus.Desc
+	saves                 *prometheus.Desc
+	// exclusions applies to the characters in instances
+	exclusions *exclusions
+}
+
+func newInstanceCollector(cfg *config.Config) Collector {
+	return &instanceCollector{
+		active: newDesc(
+			"wow_active_instances",
+			"Number of active instances",
+			[]string{"realm"},
+		),
+		byDifficulty: newDesc(
+			"wow_instances_by_difficulty",
+			"Number of instances by difficulty",
+			[]string{"realm", "difficulty"},
+		),
+		completedEncounters: newValueDesc(
+			"wow_completed_encounters",
+			"Number of completed encounters by instance",
+			[]string{"realm", "instance_id"},
+		),
+		resets: newValueDesc(
+			"wow_instance_resets",
+			"Instance reset times by map and difficulty",
+			[]string{"realm", "map_id", "difficulty"},
+		),
+		charactersInInstances: newDesc(
+			"wow_characters_in_instances",
+			"Number of characters currently in instances",
+			[]string{"realm"},
+		),
+		lfgData: newDesc(
+			"wow_lfg_data",
+			"Number of LFG entries by state",
+			[]string{"realm", "state"},
+		),
+		lagReports: newDesc(
+			"wow_lag_reports",
+			"Number of lag reports",
+			[]string{"realm"},
+		),
+		saves: newDesc(
+			"wow_instance_saves",
+			"Number of saved instance states",
+			[]string{"realm"},
+		),
+		exclusions: newExclusions(cfg.Exclusions),
+	}
+}
+
+func (c *instanceCollector) Name() string { return "instance" }
+func (c *instanceCollector) Databases() []string {
+	return c.exclusions.databases(database.CharactersDatabase)
+}
+
+func (c *instanceCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.CharactersDatabase, Table: "instance", Columns: []string{"id", "resettime", "difficulty", "completedEncounters"}},
+		{Database: database.CharactersDatabase, Table: "instance_reset", Columns: []string{"mapid", "difficulty", "resettime"}},
+		{Database: database.CharactersDatabase, Table: "character_instance", Columns: []string{"guid"}},
+		{Database: database.CharactersDatabase, Table: "lfg_data", Columns: []string{"state"}},
+		{Database: database.CharactersDatabase, Table: "lag_reports"},
+		{Database: database.CharactersDatabase, Table: "instance_saved_go_state_data"},
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"guid"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *instanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.byDifficulty
+	ch <- c.completedEncounters
+	ch <- c.resets
+	ch <- c.charactersInInstances
+	ch <- c.lfgData
+	ch <- c.lagReports
+	ch <- c.saves
+}
+
+func (c *instanceCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *instanceCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Active instances
+	query := `SELECT COUNT(*) FROM instance WHERE resettime > UNIX_TIMESTAMP()`
+	var count int
+	err := realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "active_instances", err)
+	}
+	gauge(ch, c.active, float64(count), realm.Name)
+
+	// Instances by difficulty
+	query = `SELECT difficulty, COUNT(*) FROM instance GROUP BY difficulty`
+	rows, err := realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "instances_by_difficulty", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var difficulty, count int
+		if err := rows.Scan(&difficulty, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "instances_by_difficulty", err)
+		}
+		difficultyName := constants.GetDifficultyName(difficulty)
+		gauge(ch, c.byDifficulty, float64(count), realm.Name, difficultyName)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "instances_by_difficulty", err)
+	}
+
+	// Completed encounters
+	query = `SELECT id, completedEncounters FROM instance WHERE completedEncounters > 0`
+	rows, err = realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "completed_encounters", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var instanceID, encounters int
+		if err := rows.Scan(&instanceID, &encounters); err != nil {
+			return queryFailed(database.CharactersDatabase, "completed_encounters", err)
+		}
+		gauge(ch, c.completedEncounters, float64(encounters), realm.Name, fmt.Sprintf("%d", instanceID))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "completed_encounters", err)
+	}
+
+	// Instance resets
+	query = `SELECT mapid, difficulty, resettime FROM instance_reset`
+	rows, err = realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "instance_resets", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var mapID, difficulty, resetTime int
+		if err := rows.Scan(&mapID, &difficulty, &resetTime); err != nil {
+			return queryFailed(database.CharactersDatabase, "instance_resets", err)
+		}
+		difficultyName := constants.GetDifficultyName(difficulty)
+		gauge(ch, c.resets, float64(resetTime), realm.Name, fmt.Sprintf("%d", mapID), difficultyName)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "instance_resets", err)
+	}
+
+	// Characters in instances
+	included, args, err := c.exclusions.condition(ctx, conns.Auth, realm, "c.")
+	if err != nil {
+		return err
+	}
+	query = `
+		SELECT COUNT(DISTINCT ci.guid)
+		FROM character_instance ci
+		JOIN characters c ON c.guid = ci.guid
+		WHERE ` + included
+	err = realm.Characters.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "characters_in_instances", err)
+	}
+	gauge(ch, c.charactersInInstances, float64(count), realm.Name)
+
+	// LFG data
+	query = `SELECT state, COUNT(*) FROM lfg_data GROUP BY state`
+	rows, err = realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "lfg_data", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var state, count int
+		if err := rows.Scan(&state, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "lfg_data", err)
+		}
+		stateName := constants.GetLFGStateName(state)
+		gauge(ch, c.lfgData, float64(count), realm.Name, stateName)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "lfg_data", err)
+	}
+
+	// Lag reports
+	query = `SELECT COUNT(*) FROM lag_reports`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "lag_reports", err)
+	}
+	gauge(ch, c.lagReports, float64(count), realm.Name)
+
+	// Instance saves
+	query = `SELECT COUNT(*) FROM instance_saved_go_state_data`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "instance_saves", err)
+	}
+	gauge(ch, c.saves, float64(count), realm.Name)
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+func TestInstanceCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM instance WHERE resettime > UNIX_TIMESTAMP()`).WillReturnRows(count(9))
+	db.characters.ExpectQuery(`SELECT difficulty, COUNT(*) FROM instance GROUP BY difficulty`).
+		WillReturnRows(sqlmock.NewRows([]string{"difficulty", "COUNT(*)"}).
+			AddRow(0, 6).
+			AddRow(1, 2).
+			AddRow(3, 1))
+	db.characters.ExpectQuery(`SELECT id, completedEncounters FROM instance WHERE completedEncounters > 0`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "completedEncounters"}).AddRow(101, 3))
+	db.characters.ExpectQuery(`SELECT mapid, difficulty, resettime FROM instance_reset`).
+		WillReturnRows(sqlmock.NewRows([]string{"mapid", "difficulty", "resettime"}).
+			AddRow(533, 0, 1700600000).
+			AddRow(533, 1, 1700600000))
+	db.characters.ExpectQuery(`FROM character_instance ci JOIN characters c ON c.guid = ci.guid WHERE 1`).WillReturnRows(count(25))
+	db.characters.ExpectQuery(`SELECT state, COUNT(*) FROM lfg_data GROUP BY state`).
+		WillReturnRows(sqlmock.NewRows([]string{"state", "COUNT(*)"}).
+			AddRow(2, 4).
+			AddRow(5, 10))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM lag_reports`).WillReturnRows(count(2))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM instance_saved_go_state_data`).WillReturnRows(count(40))
+
+	assertGolden(t, "instance", conns, "instance")
+}
+
+func TestInstanceCollectorExclusions(t *testing.T) {
+	cfg := testConfig()
+	cfg.Exclusions = config.ExclusionConfig{Accounts: []int{3}}
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM instance WHERE resettime > UNIX_TIMESTAMP()`).WillReturnRows(count(0))
+	db.characters.ExpectQuery(`SELECT difficulty, COUNT(*) FROM instance GROUP BY difficulty`).
+		WillReturnRows(sqlmock.NewRows([]string{"difficulty", "COUNT(*)"}))
+	db.characters.ExpectQuery(`SELECT id, completedEncounters FROM instance WHERE completedEncounters > 0`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "completedEncounters"}))
+	db.characters.ExpectQuery(`SELECT mapid, difficulty, resettime FROM instance_reset`).
+		WillReturnRows(sqlmock.NewRows([]string{"mapid", "difficulty", "resettime"}))
+	db.characters.ExpectQuery(`FROM character_instance ci JOIN characters c ON c.guid = ci.guid WHERE (c.account NOT IN (3))`).WillReturnRows(count(0))
+	db.characters.ExpectQuery(`SELECT state, COUNT(*) FROM lfg_data GROUP BY state`).
+		WillReturnRows(sqlmock.NewRows([]string{"state", "COUNT(*)"}))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM lag_reports`).WillReturnRows(count(0))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM instance_saved_go_state_data`).WillReturnRows(count(0))
+
+	runCollector(t, collectorFactories["instance"](cfg), conns)
+}
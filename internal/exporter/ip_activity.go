@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/redact"
+)
+
+func init() {
+	registerCollector("ip_activity", newIPActivityCollector)
+}
+
+// ipActivityCollector exports the most active client IP addresses, redacted
+// by the configuration
+type ipActivityCollector struct {
+	redactor     *redact.Redactor
+	activityByIP *prometheus.Desc
+}
+
+func newIPActivityCollector(cfg *config.Config) Collector {
+	return &ipActivityCollector{
+		redactor: redact.New(cfg.Redaction),
+		activityByIP: newDesc(
+			"wow_network_activity_by_ip",
+			"Network activity by IP address (top 10)",
+			[]string{"ip"},
+		),
+	}
+}
+
+func (c *ipActivityCollector) Name() string        { return "ip_activity" }
+func (c *ipActivityCollector) Databases() []string { return []string{database.AuthDatabase} }
+
+func (c *ipActivityCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.AuthDatabase, Table: "logs_ip_actions", Columns: []string{"ip"}},
+	}
+}
+
+func (c *ipActivityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.activityByIP
+}
+
+func (c *ipActivityCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	// Network activity by IP (top 10 most active IPs)
+	query := `SELECT ip, COUNT(*) as activity FROM logs_ip_actions GROUP BY ip ORDER BY activity DESC LIMIT 10`
+	rows, err := conns.Auth.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "network_activity_by_ip", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	// Redacted addresses can collide, so their activity is summed
+	activityByIP := newAccumulator()
+	for rows.Next() {
+		var ip string
+		var activity int
+		if err := rows.Scan(&ip, &activity); err != nil {
+			return queryFailed(database.AuthDatabase, "network_activity_by_ip", err)
+		}
+		activityByIP.add(float64(activity), c.redactor.IP(ip))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.AuthDatabase, "network_activity_by_ip", err)
+	}
+	activityByIP.emit(ch, c.activityByIP)
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+// expectIPActivity expects the query of the ip_activity collector
+func expectIPActivity(db *fakeDatabases) {
+	db.auth.ExpectQuery(`SELECT ip, COUNT(*) as activity FROM logs_ip_actions GROUP BY ip ORDER BY activity DESC LIMIT 10`).
+		WillReturnRows(sqlmock.NewRows([]string{"ip", "activity"}).
+			AddRow("203.0.113.7", 30).
+			AddRow("2001:db8::1", 4))
+}
+
+func TestIPActivityCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectIPActivity(db)
+
+	assertGolden(t, "ip_activity", conns, "ip_activity")
+}
+
+func TestIPActivityCollectorRedacted(t *testing.T) {
+	for _, mode := range []string{"hash", "truncate"} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Redaction = config.RedactionConfig{Salt: "secret", Character: "off", Account: "off", IP: mode}
+			conns, db := newTestConnections(t)
+			expectIPActivity(db)
+
+			assertGoldenWithConfig(t, cfg, "ip_activity", conns, "ip_activity_"+mode)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("mail", newMailCollector)
+}
+
+// mailCollector exports in-game mail metrics
+type mailCollector struct {
+	total     *prometheus.Desc
+	byFaction *prometheus.Desc
+	withItems *prometheus.Desc
+	unread    *prometheus.Desc
+	// exclusions applies to the senders of mail by faction
+	exclusions *exclusions
+}
+
+func newMailCollector(cfg *config.Config) Collector {
+	return &mailCollector{
+		total: newDesc(
+			"wow_mail_total",
+			"Total number of mail messages",
+			[]string{"realm"},
+		),
+		byFaction: newDesc(
+			"wow_mail_by_faction",
+			"Number of mail messages by faction",
+			[]string{"realm", "faction"},
+		),
+		withItems: newDesc(
+			"wow_mail_with_items",
+			"Number of mail messages with items",
+			[]string{"realm"},
+		),
+		unread: newDesc(
+			"wow_unread_mail_count",
+			"Number of unread mail messages",
+			[]string{"realm"},
+		),
+		exclusions: newExclusions(cfg.Exclusions),
+	}
+}
+
+func (c *mailCollector) Name() string { return "mail" }
+func (c *mailCollector) Databases() []string {
+	return c.exclusions.databases(database.CharactersDatabase)
+}
+
+func (c *mailCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.CharactersDatabase, Table: "mail", Columns: []string{"has_items", "checked", "sender"}},
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"guid", "race", "deleteDate"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *mailCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.byFaction
+	ch <- c.withItems
+	ch <- c.unread
+}
+
+func (c *mailCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *mailCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Query for total mail count
+	var totalMail int
+	query := `SELECT COUNT(*) FROM mail`
+	err := realm.Characters.QueryRowContext(ctx, query).Scan(&totalMail)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "total_mail_count", err)
+	}
+	gauge(ch, c.total, float64(totalMail), realm.Name)
+
+	// Query for mail with items
+	var mailWithItemsCount int
+	query = `SELECT COUNT(*) FROM mail WHERE has_items = 1`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&mailWithItemsCount)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "mail_with_items", err)
+	}
+	gauge(ch, c.withItems, float64(mailWithItemsCount), realm.Name)
+
+	// Query for unread mail
+	var unreadCount int
+	query = `SELECT COUNT(*) FROM mail WHERE checked = 0`
+	err = realm.Characters.QueryRowContext(ctx, query).Scan(&unreadCount)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "unread_mail", err)
+	}
+	gauge(ch, c.unread, float64(unreadCount), realm.Name)
+
+	// Query for mail by faction (based on sender's race)
+	included, args, err := c.exclusions.condition(ctx, conns.Auth, realm, "c.")
+	if err != nil {
+		return err
+	}
+	query = `
+		SELECT 
+			c.race,
+			COUNT(*) as count
+		FROM mail m
+		JOIN characters c ON m.sender = c.guid
+		WHERE (c.deleteDate IS NULL OR c.deleteDate = 0)
+		AND ` + included + `
+		GROUP BY c.race
+	`
+	rows, err := realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "mail_by_faction", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	byFaction := newAccumulator()
+	for rows.Next() {
+		var race, count int
+		if err := rows.Scan(&race, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "mail_by_faction", err)
+		}
+		faction := realm.Names.Faction(race)
+		if faction != "" {
+			byFaction.add(float64(count), realm.Name, faction)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "mail_by_faction", err)
+	}
+	byFaction.emit(ch, c.byFaction)
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMailCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM mail`).WillReturnRows(count(90))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM mail WHERE has_items = 1`).WillReturnRows(count(35))
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM mail WHERE checked = 0`).WillReturnRows(count(20))
+	db.characters.ExpectQuery(`FROM mail m JOIN characters c ON m.sender = c.guid`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "count"}).
+			AddRow(1, 10).
+			AddRow(3, 5).
+			AddRow(2, 8).
+			AddRow(9, 1))
+
+	assertGolden(t, "mail", conns, "mail")
+}
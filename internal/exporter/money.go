@@ -0,0 +1,189 @@
+package exporter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/constants"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/redact"
+)
+
+func init() {
+	registerCollector("money", newMoneyCollector)
+}
+
+// moneyCollector exports the money moved between characters as counters.
+// Each run reads only the log_money rows dated since the previous run, so
+// the counters start at zero when the exporter starts.
+type moneyCollector struct {
+	redactor            *redact.Redactor
+	limits              config.CardinalityConfig
+	largeTransferCopper int64
+	transferred         *prometheus.Desc
+	largeTransfers      *prometheus.Desc
+	largeCopper         *prometheus.Desc
+
+	// mu serialises runs, which share the state of every realm
+	mu     sync.Mutex
+	realms map[int]*moneyFlow
+}
+
+// moneyFlow is the money counted so far on one realm
+type moneyFlow struct {
+	// cursor is the Unix time up to which log_money has been read
+	cursor         int64
+	transferred    *accumulator
+	largeTransfers *accumulator
+	largeCopper    *accumulator
+}
+
+func newMoneyCollector(cfg *config.Config) Collector {
+	return &moneyCollector{
+		redactor:            redact.New(cfg.Redaction),
+		limits:              cfg.Cardinality,
+		largeTransferCopper: int64(cfg.Money.LargeTransferGold) * copperPerGold,
+		transferred: newDesc(
+			"wow_money_transferred_copper_total",
+			"Copper moved between characters, by log_money type",
+			[]string{"realm", "type"},
+		),
+		largeTransfers: newDesc(
+			"wow_money_large_transfers_total",
+			"Single transfers at or above the large transfer threshold, by log_money type and sending and receiving account",
+			[]string{"realm", "type", "sender_account", "receiver_account"},
+		),
+		largeCopper: newDesc(
+			"wow_money_large_transfers_copper_total",
+			"Copper moved in large transfers, by log_money type and sending and receiving account",
+			[]string{"realm", "type", "sender_account", "receiver_account"},
+		),
+		realms: make(map[int]*moneyFlow),
+	}
+}
+
+func (c *moneyCollector) Name() string        { return "money" }
+func (c *moneyCollector) Databases() []string { return []string{database.CharactersDatabase} }
+
+func (c *moneyCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.CharactersDatabase, Table: "log_money", Columns: []string{"sender_acc", "receiver_acc", "money", "date", "type"}},
+	}
+}
+
+func (c *moneyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.transferred
+	ch <- c.largeTransfers
+	ch <- c.largeCopper
+}
+
+func (c *moneyCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, realm, ch)
+	})
+}
+
+func (c *moneyCollector) updateRealm(ctx context.Context, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// The database clock bounds each run, as log_money dates come from NOW()
+	// on the database. Rows of the current second are left to the next run.
+	var now int64
+	if err := realm.Characters.QueryRowContext(ctx, `SELECT UNIX_TIMESTAMP()`).Scan(&now); err != nil {
+		return queryFailed(database.CharactersDatabase, "money_clock", err)
+	}
+
+	flow, exists := c.realms[realm.ID]
+	if !exists {
+		flow = &moneyFlow{
+			cursor:         now,
+			transferred:    newAccumulator(),
+			largeTransfers: newAccumulator(),
+			largeCopper:    newAccumulator(),
+		}
+		c.realms[realm.ID] = flow
+	}
+
+	if now > flow.cursor {
+		if err := c.read(ctx, realm, flow, now); err != nil {
+			return err
+		}
+		flow.cursor = now
+	}
+
+	flow.transferred.emitCounters(ch, c.transferred)
+	flow.largeTransfers.emitCounters(ch, c.largeTransfers)
+	flow.largeCopper.emitCounters(ch, c.largeCopper)
+	return nil
+}
+
+// read adds the log_money rows dated from the cursor of flow up to now. The
+// rows are counted only once both queries succeed, so a failed run is
+// repeated in full by the next one.
+func (c *moneyCollector) read(ctx context.Context, realm *database.Realm, flow *moneyFlow, now int64) error {
+	query := `
+		SELECT type, SUM(money)
+		FROM log_money
+		WHERE date >= FROM_UNIXTIME(?) AND date < FROM_UNIXTIME(?)
+		GROUP BY type
+	`
+	rows, err := realm.Characters.QueryContext(ctx, query, flow.cursor, now)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "money_transferred", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	transferred := newAccumulator()
+	for rows.Next() {
+		var moneyType int
+		var copper float64
+		if err := rows.Scan(&moneyType, &copper); err != nil {
+			return queryFailed(database.CharactersDatabase, "money_transferred", err)
+		}
+		transferred.add(copper, realm.Name, constants.GetMoneyTypeName(moneyType))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "money_transferred", err)
+	}
+
+	largeTransfers, largeCopper := newAccumulator(), newAccumulator()
+	if c.largeTransferCopper > 0 {
+		query = `
+			SELECT type, sender_acc, receiver_acc, COUNT(*), SUM(money)
+			FROM log_money
+			WHERE date >= FROM_UNIXTIME(?) AND date < FROM_UNIXTIME(?)
+			AND money >= ?
+			GROUP BY type, sender_acc, receiver_acc
+		`
+		rows, err := realm.Characters.QueryContext(ctx, query, flow.cursor, now, c.largeTransferCopper)
+		if err != nil {
+			return queryFailed(database.CharactersDatabase, "large_money_transfers", err)
+		}
+		defer database.CloseRowsWithLog(rows)
+
+		for rows.Next() {
+			var moneyType, sender, receiver, count int
+			var copper float64
+			if err := rows.Scan(&moneyType, &sender, &receiver, &count, &copper); err != nil {
+				return queryFailed(database.CharactersDatabase, "large_money_transfers", err)
+			}
+			labels := []string{realm.Name, constants.GetMoneyTypeName(moneyType), c.redactor.Account(strconv.Itoa(sender)), c.redactor.Account(strconv.Itoa(receiver))}
+			largeTransfers.add(float64(count), labels...)
+			largeCopper.add(copper, labels...)
+		}
+		if err := rows.Err(); err != nil {
+			return queryFailed(database.CharactersDatabase, "large_money_transfers", err)
+		}
+	}
+
+	flow.transferred.merge(transferred)
+	// Every account pair adds series for as long as the exporter runs, so
+	// the pairs over the series limit are kept as a single "other" series
+	flow.largeTransfers.mergeLimited(largeTransfers, c.limits.MaxSeriesFor("wow_money_large_transfers_total"))
+	flow.largeCopper.mergeLimited(largeCopper, c.limits.MaxSeriesFor("wow_money_large_transfers_copper_total"))
+	return nil
+}
@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+func TestMoneyCollector(t *testing.T) {
+	cfg := testConfig()
+	cfg.Money.LargeTransferGold = 10000
+	conns, db := newTestConnections(t)
+	c := collectorFactories["money"](cfg)
+	clock := func(now int) {
+		db.characters.ExpectQuery(`SELECT UNIX_TIMESTAMP()`).
+			WillReturnRows(sqlmock.NewRows([]string{"UNIX_TIMESTAMP()"}).AddRow(now))
+	}
+	transferred := `SELECT type, SUM(money) FROM log_money WHERE date >= FROM_UNIXTIME(?) AND date < FROM_UNIXTIME(?) GROUP BY type`
+	large := `SELECT type, sender_acc, receiver_acc, COUNT(*), SUM(money) FROM log_money WHERE date >= FROM_UNIXTIME(?) AND date < FROM_UNIXTIME(?) AND money >= ? GROUP BY type, sender_acc, receiver_acc`
+
+	// The first run starts counting from the current time
+	clock(1000)
+	runCollector(t, c, conns)
+
+	clock(1060)
+	db.characters.ExpectQuery(transferred).WithArgs(1000, 1060).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "SUM(money)"}).
+			AddRow(5, "250000").
+			AddRow(6, "120000000"))
+	db.characters.ExpectQuery(large).WithArgs(1000, 1060, 100000000).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "sender_acc", "receiver_acc", "COUNT(*)", "SUM(money)"}).
+			AddRow(6, 12, 34, 1, "120000000"))
+	runCollector(t, c, conns)
+
+	// A run within the same second reads nothing
+	clock(1060)
+	runCollector(t, c, conns)
+
+	clock(1120)
+	db.characters.ExpectQuery(transferred).WithArgs(1060, 1120).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "SUM(money)"}).
+			AddRow(5, "50000").
+			AddRow(1, "7500"))
+	db.characters.ExpectQuery(large).WithArgs(1060, 1120, 100000000).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "sender_acc", "receiver_acc", "COUNT(*)", "SUM(money)"}).
+			AddRow(6, 12, 34, 1, "100000000").
+			AddRow(6, 56, 34, 1, "200000000"))
+
+	assertCollectorGolden(t, c, conns, "money")
+}
+
+func TestMoneyCollectorRedactsAccounts(t *testing.T) {
+	cfg := testConfig()
+	cfg.Money.LargeTransferGold = 10000
+	cfg.Redaction = config.RedactionConfig{Salt: "secret", Character: "off", Account: "hash", IP: "off"}
+	conns, db := newTestConnections(t)
+	c := collectorFactories["money"](cfg)
+
+	db.characters.ExpectQuery(`SELECT UNIX_TIMESTAMP()`).
+		WillReturnRows(sqlmock.NewRows([]string{"UNIX_TIMESTAMP()"}).AddRow(1000))
+	runCollector(t, c, conns)
+
+	db.characters.ExpectQuery(`SELECT UNIX_TIMESTAMP()`).
+		WillReturnRows(sqlmock.NewRows([]string{"UNIX_TIMESTAMP()"}).AddRow(1060))
+	db.characters.ExpectQuery(`GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "SUM(money)"}).AddRow(6, "120000000"))
+	db.characters.ExpectQuery(`GROUP BY type, sender_acc, receiver_acc`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "sender_acc", "receiver_acc", "COUNT(*)", "SUM(money)"}).
+			AddRow(6, 12, 34, 1, "120000000"))
+
+	assertCollectorGolden(t, c, conns, "money_redacted")
+}
+
+func TestMoneyCollectorFoldsAccountPairsOverTheSeriesLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.Money.LargeTransferGold = 10000
+	cfg.Cardinality.MetricMaxSeries = map[string]int{
+		"wow_money_large_transfers_total":        1,
+		"wow_money_large_transfers_copper_total": 1,
+	}
+	conns, db := newTestConnections(t)
+	c := collectorFactories["money"](cfg)
+	clock := func(now int) {
+		db.characters.ExpectQuery(`SELECT UNIX_TIMESTAMP()`).
+			WillReturnRows(sqlmock.NewRows([]string{"UNIX_TIMESTAMP()"}).AddRow(now))
+	}
+	transfers := func(rows *sqlmock.Rows) {
+		db.characters.ExpectQuery(`GROUP BY type`).
+			WillReturnRows(sqlmock.NewRows([]string{"type", "SUM(money)"}))
+		db.characters.ExpectQuery(`GROUP BY type, sender_acc, receiver_acc`).WillReturnRows(rows)
+	}
+	columns := []string{"type", "sender_acc", "receiver_acc", "COUNT(*)", "SUM(money)"}
+
+	clock(1000)
+	runCollector(t, c, conns)
+
+	// The pair seen first keeps its series, later pairs only add to "other"
+	clock(1060)
+	transfers(sqlmock.NewRows(columns).
+		AddRow(6, 12, 34, 1, "120000000").
+		AddRow(6, 56, 34, 1, "200000000"))
+	runCollector(t, c, conns)
+
+	clock(1120)
+	transfers(sqlmock.NewRows(columns).
+		AddRow(6, 12, 34, 1, "100000000").
+		AddRow(6, 78, 90, 2, "300000000"))
+
+	assertCollectorGolden(t, c, conns, "money_limited")
+}
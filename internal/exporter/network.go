@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/constants"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("network", newNetworkCollector)
+}
+
+// networkCollector exports latency, lag and IP activity metrics
+type networkCollector struct {
+	latencyStats       *prometheus.Desc
+	ipBanned           *prometheus.Desc
+	ipActionLogsByType *prometheus.Desc
+	lagReportsByType   *prometheus.Desc
+	averageLatency     *prometheus.Desc
+	highLatencyPlayers *prometheus.Desc
+	exclusions         *exclusions
+}
+
+func newNetworkCollector(cfg *config.Config) Collector {
+	return &networkCollector{
+		latencyStats: newValueDesc(
+			"wow_player_latency",
+			"Player latency statistics",
+			[]string{"realm", "stat"},
+		),
+		ipBanned: newDesc(
+			"wow_ip_banned_count",
+			"Number of banned IP addresses",
+			nil,
+		),
+		ipActionLogsByType: newDesc(
+			"wow_ip_action_logs_by_type",
+			"Number of IP action logs by type",
+			[]string{"type"},
+		),
+		lagReportsByType: newDesc(
+			"wow_lag_reports_by_type",
+			"Number of lag reports by type",
+			[]string{"realm", "lag_type"},
+		),
+		averageLatency: newValueDesc(
+			"wow_average_latency_ms",
+			"Average player latency in milliseconds",
+			[]string{"realm"},
+		),
+		highLatencyPlayers: newDesc(
+			"wow_high_latency_players",
+			"Number of players with high latency (>200ms)",
+			[]string{"realm"},
+		),
+		exclusions: newExclusions(cfg.Exclusions),
+	}
+}
+
+func (c *networkCollector) Name() string { return "network" }
+func (c *networkCollector) Databases() []string {
+	return []string{database.AuthDatabase, database.CharactersDatabase}
+}
+
+func (c *networkCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.AuthDatabase, Table: "ip_banned"},
+		{Database: database.AuthDatabase, Table: "logs_ip_actions", Columns: []string{"type"}},
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"online", "latency", "deleteDate"}},
+		{Database: database.CharactersDatabase, Table: "lag_reports", Columns: []string{"lagType"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *networkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.latencyStats
+	ch <- c.ipBanned
+	ch <- c.ipActionLogsByType
+	ch <- c.lagReportsByType
+	ch <- c.averageLatency
+	ch <- c.highLatencyPlayers
+}
+
+func (c *networkCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	// IP bans
+	var bannedCount int
+	query := `SELECT COUNT(*) FROM ip_banned`
+	err := conns.Auth.QueryRowContext(ctx, query).Scan(&bannedCount)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "ip_bans", err)
+	}
+	gauge(ch, c.ipBanned, float64(bannedCount))
+
+	// IP action logs by type
+	query = `SELECT type, COUNT(*) FROM logs_ip_actions GROUP BY type`
+	rows, err := conns.Auth.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "ip_action_logs_by_type", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var actionType, count int
+		if err := rows.Scan(&actionType, &count); err != nil {
+			return queryFailed(database.AuthDatabase, "ip_action_logs_by_type", err)
+		}
+		typeName := constants.GetIPActionTypeName(actionType)
+		gauge(ch, c.ipActionLogsByType, float64(count), typeName)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.AuthDatabase, "ip_action_logs_by_type", err)
+	}
+
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *networkCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	included, args, err := c.exclusions.condition(ctx, conns.Auth, realm, "")
+	if err != nil {
+		return err
+	}
+
+	// Average latency
+	var avgLatency sql.NullFloat64
+	query := `
+		SELECT AVG(latency) 
+		FROM characters 
+		WHERE online = 1 
+		AND latency > 0 
+		AND (deleteDate IS NULL OR deleteDate = 0)
+		AND ` + included + `
+	`
+	err = realm.Characters.QueryRowContext(ctx, query, args...).Scan(&avgLatency)
+	if err != nil && err != sql.ErrNoRows {
+		return queryFailed(database.CharactersDatabase, "average_latency", err)
+	}
+	if err != sql.ErrNoRows && avgLatency.Valid {
+		gauge(ch, c.latencyStats, avgLatency.Float64, realm.Name, "average")
+		gauge(ch, c.averageLatency, avgLatency.Float64, realm.Name)
+	}
+
+	// High latency players (>200ms)
+	var highLatencyCount int
+	query = `
+		SELECT COUNT(*) 
+		FROM characters 
+		WHERE online = 1 
+		AND latency > 200 
+		AND (deleteDate IS NULL OR deleteDate = 0)
+		AND ` + included + `
+	`
+	err = realm.Characters.QueryRowContext(ctx, query, args...).Scan(&highLatencyCount)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "high_latency_players", err)
+	}
+	gauge(ch, c.highLatencyPlayers, float64(highLatencyCount), realm.Name)
+	gauge(ch, c.latencyStats, float64(highLatencyCount), realm.Name, "high_latency")
+
+	// Min/Max latency
+	var minLatency, maxLatency sql.NullInt64
+	query = `
+		SELECT MIN(latency), MAX(latency) 
+		FROM characters 
+		WHERE online = 1 
+		AND latency > 0 
+		AND (deleteDate IS NULL OR deleteDate = 0)
+		AND ` + included + `
+	`
+	err = realm.Characters.QueryRowContext(ctx, query, args...).Scan(&minLatency, &maxLatency)
+	if err != nil && err != sql.ErrNoRows {
+		return queryFailed(database.CharactersDatabase, "min_max_latency", err)
+	}
+	if err != sql.ErrNoRows {
+		if minLatency.Valid {
+			gauge(ch, c.latencyStats, float64(minLatency.Int64), realm.Name, "min")
+		}
+		if maxLatency.Valid {
+			gauge(ch, c.latencyStats, float64(maxLatency.Int64), realm.Name, "max")
+		}
+	}
+
+	// Lag reports by type
+	query = `SELECT lagType, COUNT(*) FROM lag_reports GROUP BY lagType`
+	rows, err := realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "lag_reports_by_type", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+	for rows.Next() {
+		var lagType, count int
+		if err := rows.Scan(&lagType, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "lag_reports_by_type", err)
+		}
+		lagTypeName := constants.GetLagTypeName(lagType)
+		gauge(ch, c.lagReportsByType, float64(count), realm.Name, lagTypeName)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "lag_reports_by_type", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNetworkCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`SELECT COUNT(*) FROM ip_banned`).WillReturnRows(count(5))
+	db.auth.ExpectQuery(`SELECT type, COUNT(*) FROM logs_ip_actions GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "COUNT(*)"}).
+			AddRow(0, 60).
+			AddRow(1, 7))
+	db.characters.ExpectQuery(`SELECT AVG(latency) FROM characters WHERE online = 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"AVG(latency)"}).AddRow(85.5))
+	db.characters.ExpectQuery(`AND latency > 200`).WillReturnRows(count(2))
+	db.characters.ExpectQuery(`SELECT MIN(latency), MAX(latency)`).
+		WillReturnRows(sqlmock.NewRows([]string{"MIN(latency)", "MAX(latency)"}).AddRow(20, 340))
+	db.characters.ExpectQuery(`SELECT lagType, COUNT(*) FROM lag_reports GROUP BY lagType`).
+		WillReturnRows(sqlmock.NewRows([]string{"lagType", "COUNT(*)"}).AddRow(1, 3))
+
+	assertGolden(t, "network", conns, "network")
+}
+
+func TestNetworkCollectorWithoutOnlinePlayers(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`SELECT COUNT(*) FROM ip_banned`).WillReturnRows(count(0))
+	db.auth.ExpectQuery(`FROM logs_ip_actions GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "COUNT(*)"}))
+	db.characters.ExpectQuery(`SELECT AVG(latency)`).
+		WillReturnRows(sqlmock.NewRows([]string{"AVG(latency)"}).AddRow(nil))
+	db.characters.ExpectQuery(`AND latency > 200`).WillReturnRows(count(0))
+	db.characters.ExpectQuery(`SELECT MIN(latency), MAX(latency)`).
+		WillReturnRows(sqlmock.NewRows([]string{"MIN(latency)", "MAX(latency)"}).AddRow(nil, nil))
+	db.characters.ExpectQuery(`FROM lag_reports GROUP BY lagType`).
+		WillReturnRows(sqlmock.NewRows([]string{"lagType", "COUNT(*)"}))
+
+	assertGolden(t, "network", conns, "network_idle")
+}
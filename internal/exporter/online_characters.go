@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/redact"
+)
+
+func init() {
+	registerCollector("online_characters", newOnlineCharactersCollector)
+}
+
+// onlineCharactersCollector exports one series per online character, labelled
+// with the character and account name as redacted by the configuration
+type onlineCharactersCollector struct {
+	redactor      *redact.Redactor
+	exclusions    *exclusions
+	onlineByLevel *prometheus.Desc
+}
+
+func newOnlineCharactersCollector(cfg *config.Config) Collector {
+	return &onlineCharactersCollector{
+		redactor:   redact.New(cfg.Redaction),
+		exclusions: newExclusions(cfg.Exclusions),
+		onlineByLevel: newValueDesc(
+			"wow_online_players_by_level",
+			"Online characters by name and account, value is the character's level",
+			[]string{"realm", "character_name", "account_name"},
+		),
+	}
+}
+
+func (c *onlineCharactersCollector) Name() string { return "online_characters" }
+func (c *onlineCharactersCollector) Databases() []string {
+	return []string{database.AuthDatabase, database.CharactersDatabase}
+}
+
+func (c *onlineCharactersCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"name", "level", "account", "online", "deleteDate"}},
+		{Database: database.AuthDatabase, Table: "account", Columns: []string{"id", "username"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *onlineCharactersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.onlineByLevel
+}
+
+func (c *onlineCharactersCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *onlineCharactersCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Query for online players by level with character and account name
+	// First get character information from characters database
+	included, args, err := c.exclusions.condition(ctx, conns.Auth, realm, "c.")
+	if err != nil {
+		return err
+	}
+	query := `
+		SELECT 
+			c.name,
+			c.level,
+			c.account
+		FROM characters c
+		WHERE c.online = 1
+		AND (c.deleteDate IS NULL OR c.deleteDate = 0)
+		AND ` + included + `
+		ORDER BY c.level, c.name
+	`
+	rows, err := realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "online_characters", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	// Create a map to store account IDs and their usernames
+	accountMap := make(map[int]string)
+
+	// Redacted names can collide; such characters share a series reporting
+	// the highest level
+	type characterKey struct{ character, account string }
+	levels := make(map[characterKey]int)
+	var keys []characterKey
+
+	for rows.Next() {
+		var characterName string
+		var level, accountID int
+		if err := rows.Scan(&characterName, &level, &accountID); err != nil {
+			return queryFailed(database.CharactersDatabase, "online_characters", err)
+		}
+
+		// Get account username if we haven't already
+		accountName, exists := accountMap[accountID]
+		if !exists {
+			// Query auth database for account username
+			var username string
+			authQuery := `SELECT username FROM account WHERE id = ?`
+			err := conns.Auth.QueryRowContext(ctx, authQuery, accountID).Scan(&username)
+			if err != nil {
+				// If we can't get the username, use a placeholder
+				username = fmt.Sprintf("account_%d", accountID)
+			}
+			accountMap[accountID] = username
+			accountName = username
+		}
+
+		key := characterKey{c.redactor.Character(characterName), c.redactor.Account(accountName)}
+		current, exists := levels[key]
+		if !exists {
+			keys = append(keys, key)
+		}
+		levels[key] = max(current, level)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "online_characters", err)
+	}
+
+	for _, key := range keys {
+		gauge(ch, c.onlineByLevel, float64(levels[key]), realm.Name, key.character, key.account)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+// expectOnlineCharacters expects the queries of the online_characters
+// collector
+func expectOnlineCharacters(db *fakeDatabases) {
+	db.characters.ExpectQuery(`SELECT c.name, c.level, c.account FROM characters c WHERE c.online = 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "level", "account"}).
+			AddRow("Arthas", 80, 1).
+			AddRow("Jaina", 78, 2).
+			AddRow("Thrall", 80, 1))
+	// Account names are looked up once per account
+	db.auth.ExpectQuery(`SELECT username FROM account WHERE id = ?`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("LICHKING"))
+	db.auth.ExpectQuery(`SELECT username FROM account WHERE id = ?`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}))
+}
+
+func TestOnlineCharactersCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectOnlineCharacters(db)
+
+	assertGolden(t, "online_characters", conns, "online_characters")
+}
+
+func TestOnlineCharactersCollectorRedacted(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redaction = config.RedactionConfig{Salt: "secret", Character: "hash", Account: "truncate", IP: "off"}
+	conns, db := newTestConnections(t)
+	expectOnlineCharacters(db)
+
+	assertGoldenWithConfig(t, cfg, "online_characters", conns, "online_characters_redacted")
+}
@@ -0,0 +1,209 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("playerbots", newPlayerbotsCollector)
+}
+
+// playerbotsCollector exports the state of the playerbots module. Realms
+// without a playerbots database are skipped.
+type playerbotsCollector struct {
+	randomBots   *prometheus.Desc
+	byLevel      *prometheus.Desc
+	byClass      *prometheus.Desc
+	guildTasks   *prometheus.Desc
+	accountLinks *prometheus.Desc
+}
+
+func newPlayerbotsCollector(cfg *config.Config) Collector {
+	return &playerbotsCollector{
+		randomBots: newDesc(
+			"wow_playerbots_random_bots",
+			"Number of random bots with a pending event, by event",
+			[]string{"realm", "event"},
+		),
+		byLevel: newDesc(
+			"wow_playerbots_random_bots_by_level",
+			"Number of random bots by level",
+			[]string{"realm", "level"},
+		),
+		byClass: newDesc(
+			"wow_playerbots_random_bots_by_class",
+			"Number of random bots by class",
+			[]string{"realm", "class"},
+		),
+		guildTasks: newDesc(
+			"wow_playerbots_guild_tasks",
+			"Number of outstanding guild tasks, by type",
+			[]string{"realm", "type"},
+		),
+		accountLinks: newDesc(
+			"wow_playerbots_account_links",
+			"Number of links between player accounts and bot accounts",
+			[]string{"realm"},
+		),
+	}
+}
+
+func (c *playerbotsCollector) Name() string { return "playerbots" }
+func (c *playerbotsCollector) Databases() []string {
+	return []string{database.CharactersDatabase, database.PlayerbotsDatabase}
+}
+
+func (c *playerbotsCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"guid", "level", "class"}},
+		{Database: database.PlayerbotsDatabase, Table: "playerbots_random_bots", Columns: []string{"bot", "event", "time", "validIn"}},
+		{Database: database.PlayerbotsDatabase, Table: "playerbots_guild_tasks", Columns: []string{"type", "time", "validIn"}},
+		{Database: database.PlayerbotsDatabase, Table: "playerbots_account_links"},
+	}
+}
+
+func (c *playerbotsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.randomBots
+	ch <- c.byLevel
+	ch <- c.byClass
+	ch <- c.guildTasks
+	ch <- c.accountLinks
+}
+
+func (c *playerbotsCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		if realm.Playerbots == nil {
+			return nil
+		}
+		return c.updateRealm(ctx, realm, ch)
+	})
+}
+
+func (c *playerbotsCollector) updateRealm(ctx context.Context, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// The module keeps one row per bot and event and ignores rows past
+	// time + validIn; bot 0 holds the state of the manager itself
+	query := `
+		SELECT event, COUNT(DISTINCT bot)
+		FROM playerbots_random_bots
+		WHERE bot <> 0
+		AND time + validIn > UNIX_TIMESTAMP()
+		GROUP BY event
+	`
+	rows, err := realm.Playerbots.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.PlayerbotsDatabase, "random_bots_by_event", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		// event and type are nullable, NULL is exported as an empty label
+		var event sql.NullString
+		var count int
+		if err := rows.Scan(&event, &count); err != nil {
+			return queryFailed(database.PlayerbotsDatabase, "random_bots_by_event", err)
+		}
+		gauge(ch, c.randomBots, float64(count), realm.Name, event.String)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.PlayerbotsDatabase, "random_bots_by_event", err)
+	}
+
+	if err := c.updateBotCharacters(ctx, realm, ch); err != nil {
+		return err
+	}
+
+	query = `
+		SELECT type, COUNT(*)
+		FROM playerbots_guild_tasks
+		WHERE time + validIn > UNIX_TIMESTAMP()
+		GROUP BY type
+	`
+	rows, err = realm.Playerbots.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.PlayerbotsDatabase, "guild_tasks", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var taskType sql.NullString
+		var count int
+		if err := rows.Scan(&taskType, &count); err != nil {
+			return queryFailed(database.PlayerbotsDatabase, "guild_tasks", err)
+		}
+		gauge(ch, c.guildTasks, float64(count), realm.Name, taskType.String)
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.PlayerbotsDatabase, "guild_tasks", err)
+	}
+
+	var links int
+	query = `SELECT COUNT(*) FROM playerbots_account_links`
+	if err := realm.Playerbots.QueryRowContext(ctx, query).Scan(&links); err != nil {
+		return queryFailed(database.PlayerbotsDatabase, "account_links", err)
+	}
+	gauge(ch, c.accountLinks, float64(links), realm.Name)
+
+	return nil
+}
+
+// updateBotCharacters exports the levels and classes of the random bots,
+// read from the characters database as the playerbots database may be on
+// another server
+func (c *playerbotsCollector) updateBotCharacters(ctx context.Context, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	guids, err := queryIDs(ctx, realm.Playerbots, `SELECT DISTINCT bot FROM playerbots_random_bots WHERE bot <> 0`)
+	if err != nil {
+		return queryFailed(database.PlayerbotsDatabase, "random_bots", err)
+	}
+	if len(guids) == 0 {
+		return nil
+	}
+	random := (&bots{guids: guids}).condition("")
+
+	query := `SELECT level, COUNT(*) FROM characters WHERE ` + random + ` GROUP BY level`
+	rows, err := realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "random_bots_by_level", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	for rows.Next() {
+		var level, count int
+		if err := rows.Scan(&level, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "random_bots_by_level", err)
+		}
+		gauge(ch, c.byLevel, float64(count), realm.Name, fmt.Sprintf("%d", level))
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "random_bots_by_level", err)
+	}
+
+	query = `SELECT class, COUNT(*) FROM characters WHERE ` + random + ` GROUP BY class`
+	rows, err = realm.Characters.QueryContext(ctx, query)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "random_bots_by_class", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	byClass := newAccumulator()
+	for rows.Next() {
+		var class, count int
+		if err := rows.Scan(&class, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "random_bots_by_class", err)
+		}
+		if className := realm.Names.Class(class); className != "" {
+			byClass.add(float64(count), realm.Name, className)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "random_bots_by_class", err)
+	}
+	byClass.emit(ch, c.byClass)
+
+	return nil
+}
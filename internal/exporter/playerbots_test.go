@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPlayerbotsCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.addPlayerbots(t, conns)
+	db.playerbots.ExpectQuery(`FROM playerbots_random_bots WHERE bot <> 0 AND time + validIn > UNIX_TIMESTAMP() GROUP BY event`).
+		WillReturnRows(sqlmock.NewRows([]string{"event", "COUNT(DISTINCT bot)"}).
+			AddRow("add", 3).
+			AddRow("login", 2).
+			AddRow("teleport", 1).
+			AddRow(nil, 1))
+	db.playerbots.ExpectQuery(`SELECT DISTINCT bot FROM playerbots_random_bots WHERE bot <> 0`).
+		WillReturnRows(sqlmock.NewRows([]string{"bot"}).AddRow(101).AddRow(102).AddRow(103))
+	db.characters.ExpectQuery(`FROM characters WHERE (guid IN (101, 102, 103)) GROUP BY level`).
+		WillReturnRows(sqlmock.NewRows([]string{"level", "COUNT(*)"}).
+			AddRow(60, 1).
+			AddRow(80, 2))
+	db.characters.ExpectQuery(`FROM characters WHERE (guid IN (101, 102, 103)) GROUP BY class`).
+		WillReturnRows(sqlmock.NewRows([]string{"class", "COUNT(*)"}).
+			AddRow(1, 2).
+			AddRow(8, 1))
+	db.playerbots.ExpectQuery(`FROM playerbots_guild_tasks WHERE time + validIn > UNIX_TIMESTAMP() GROUP BY type`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "COUNT(*)"}).
+			AddRow("activeTask", 4).
+			AddRow("killTask", 2).
+			AddRow(nil, 1))
+	db.playerbots.ExpectQuery(`SELECT COUNT(*) FROM playerbots_account_links`).WillReturnRows(count(5))
+
+	assertGolden(t, "playerbots", conns, "playerbots")
+}
+
+func TestPlayerbotsCollectorWithoutPlayerbots(t *testing.T) {
+	conns, _ := newTestConnections(t)
+
+	assertGolden(t, "playerbots", conns, "playerbots_none")
+}
@@ -0,0 +1,296 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("players", newPlayersCollector)
+}
+
+// playersCollector exports character population metrics
+type playersCollector struct {
+	online           *prometheus.Desc
+	total            *prometheus.Desc
+	byLevel          *prometheus.Desc
+	byClass          *prometheus.Desc
+	maxLevel         *prometheus.Desc
+	bannedCharacters *prometheus.Desc
+	exclusions       *exclusions
+}
+
+func newPlayersCollector(cfg *config.Config) Collector {
+	return &playersCollector{
+		online: newDesc(
+			"wow_players_online",
+			"Number of players currently online",
+			[]string{"realm", "faction", "is_bot"},
+		),
+		total: newDesc(
+			"wow_players_total",
+			"Total number of players",
+			[]string{"realm", "faction", "is_bot"},
+		),
+		byLevel: newDesc(
+			"wow_players_by_level",
+			"Number of players by level",
+			[]string{"realm", "level", "faction", "is_bot"},
+		),
+		byClass: newDesc(
+			"wow_players_by_class",
+			"Number of players by class",
+			[]string{"realm", "class", "faction", "is_bot"},
+		),
+		maxLevel: newDesc(
+			"wow_max_level_characters",
+			"Number of max-level characters by faction",
+			[]string{"realm", "faction"},
+		),
+		bannedCharacters: newDesc(
+			"wow_banned_characters",
+			"Number of banned characters",
+			[]string{"realm"},
+		),
+		exclusions: newExclusions(cfg.Exclusions),
+	}
+}
+
+func (c *playersCollector) Name() string { return "players" }
+func (c *playersCollector) Databases() []string {
+	return c.exclusions.databases(database.CharactersDatabase)
+}
+
+func (c *playersCollector) Requirements() []database.Requirement {
+	return append([]database.Requirement{
+		{Database: database.CharactersDatabase, Table: "characters", Columns: []string{"guid", "account", "race", "class", "level", "online", "logout_time", "deleteDate"}},
+		{Database: database.CharactersDatabase, Table: "character_banned", Columns: []string{"guid", "active"}},
+	}, c.exclusions.requirements()...)
+}
+
+func (c *playersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.online
+	ch <- c.total
+	ch <- c.byLevel
+	ch <- c.byClass
+	ch <- c.maxLevel
+	ch <- c.bannedCharacters
+}
+
+func (c *playersCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *playersCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// While bots cannot be told from players the metrics labelled by is_bot
+	// are left out, and the error is returned once the others are sent
+	bots, botsErr := loadBots(ctx, realm)
+	if botsErr != nil && !errors.Is(botsErr, database.ErrUnavailable) {
+		return botsErr
+	}
+	included, args, err := c.exclusions.condition(ctx, conns.Auth, realm, "")
+	if err != nil {
+		return err
+	}
+
+	if bots != nil {
+		if err := c.updatePopulation(ctx, realm, bots.condition(""), included, args, ch); err != nil {
+			return err
+		}
+	}
+
+	// AzerothCore WotLK max level is 80
+	query := `
+		SELECT race, COUNT(*) 
+		FROM characters 
+		WHERE level = 80 
+		AND (deleteDate IS NULL OR deleteDate = 0)
+		AND ` + included + `
+		GROUP BY race
+	`
+	rows, err := realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "max_level_characters", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	maxLevel := newAccumulator()
+	for rows.Next() {
+		var race, count int
+		if err := rows.Scan(&race, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "max_level_characters", err)
+		}
+		faction := realm.Names.Faction(race)
+		if faction != "" {
+			maxLevel.add(float64(count), realm.Name, faction)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "max_level_characters", err)
+	}
+	maxLevel.emit(ch, c.maxLevel)
+
+	// Banned characters
+	var banned int
+	query = `SELECT COUNT(DISTINCT guid) FROM character_banned WHERE active = 1`
+	if err := realm.Characters.QueryRowContext(ctx, query).Scan(&banned); err != nil {
+		return queryFailed(database.CharactersDatabase, "banned_characters", err)
+	}
+	gauge(ch, c.bannedCharacters, float64(banned), realm.Name)
+
+	return botsErr
+}
+
+// updatePopulation sends the metrics of realm labelled by is_bot, which
+// isBot computes for each character
+func (c *playersCollector) updatePopulation(ctx context.Context, realm *database.Realm, isBot, included string, args []any, ch chan<- prometheus.Metric) error {
+	// Query for online players by faction
+	query := `
+		SELECT 
+			race,
+			` + isBot + ` AS is_bot,
+			COUNT(*) as count
+		FROM characters 
+		WHERE online = 1 
+		AND ` + included + `
+		GROUP BY race, is_bot
+	`
+	rows, err := realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "online_players_by_faction", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	online := newAccumulator()
+	for rows.Next() {
+		var race, count int
+		var bot bool
+		if err := rows.Scan(&race, &bot, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "online_players_by_faction", err)
+		}
+		faction := realm.Names.Faction(race)
+		if faction != "" {
+			online.add(float64(count), realm.Name, faction, strconv.FormatBool(bot))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "online_players_by_faction", err)
+	}
+	online.emit(ch, c.online)
+
+	// Query for total players by faction
+	query = `
+		SELECT 
+			race,
+			` + isBot + ` AS is_bot,
+			COUNT(*) as count
+		FROM characters 
+		WHERE (deleteDate IS NULL OR deleteDate = 0)
+		AND ` + included + `
+		GROUP BY race, is_bot
+	`
+	rows, err = realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "total_players_by_faction", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	total := newAccumulator()
+	for rows.Next() {
+		var race, count int
+		var bot bool
+		if err := rows.Scan(&race, &bot, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "total_players_by_faction", err)
+		}
+		faction := realm.Names.Faction(race)
+		if faction != "" {
+			total.add(float64(count), realm.Name, faction, strconv.FormatBool(bot))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "total_players_by_faction", err)
+	}
+	total.emit(ch, c.total)
+
+	// Query for players by level and faction
+	query = `
+		SELECT 
+			level,
+			race,
+			` + isBot + ` AS is_bot,
+			COUNT(*) as count
+		FROM characters 
+		WHERE (deleteDate IS NULL OR deleteDate = 0)
+		AND logout_time > 0 -- Exclude characters that have never logged in
+		AND ` + included + `
+		GROUP BY level, race, is_bot
+	`
+	rows, err = realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "players_by_level", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	byLevel := newAccumulator()
+	for rows.Next() {
+		var level, race, count int
+		var bot bool
+		if err := rows.Scan(&level, &race, &bot, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "players_by_level", err)
+		}
+		faction := realm.Names.Faction(race)
+		if faction != "" {
+			byLevel.add(float64(count), realm.Name, fmt.Sprintf("%d", level), faction, strconv.FormatBool(bot))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "players_by_level", err)
+	}
+	byLevel.emit(ch, c.byLevel)
+
+	// Query for players by class and faction
+	query = `
+		SELECT 
+			class,
+			race,
+			` + isBot + ` AS is_bot,
+			COUNT(*) as count
+		FROM characters 
+		WHERE (deleteDate IS NULL OR deleteDate = 0)
+		AND ` + included + `
+		GROUP BY class, race, is_bot
+	`
+	rows, err = realm.Characters.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryFailed(database.CharactersDatabase, "players_by_class", err)
+	}
+	defer database.CloseRowsWithLog(rows)
+
+	byClass := newAccumulator()
+	for rows.Next() {
+		var class, race, count int
+		var bot bool
+		if err := rows.Scan(&class, &race, &bot, &count); err != nil {
+			return queryFailed(database.CharactersDatabase, "players_by_class", err)
+		}
+		faction := realm.Names.Faction(race)
+		className := realm.Names.Class(class)
+		if faction != "" && className != "" {
+			byClass.add(float64(count), realm.Name, className, faction, strconv.FormatBool(bot))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(database.CharactersDatabase, "players_by_class", err)
+	}
+	byClass.emit(ch, c.byClass)
+
+	return nil
+}
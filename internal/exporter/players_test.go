@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func TestPlayersCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.characters.ExpectQuery(`0 AS is_bot, COUNT(*) as count FROM characters WHERE online = 1 AND 1 GROUP BY race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).
+			AddRow(1, 0, 4).
+			AddRow(4, 0, 2).
+			AddRow(2, 0, 5))
+	db.characters.ExpectQuery(`WHERE (deleteDate IS NULL OR deleteDate = 0) AND 1 GROUP BY race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).
+			AddRow(1, 0, 40).
+			AddRow(11, 0, 12).
+			AddRow(5, 0, 30).
+			AddRow(10, 0, 18))
+	db.characters.ExpectQuery(`GROUP BY level, race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"level", "race", "is_bot", "count"}).
+			AddRow(80, 1, 0, 10).
+			AddRow(80, 3, 0, 2).
+			AddRow(80, 2, 0, 7).
+			AddRow(12, 6, 0, 1))
+	db.characters.ExpectQuery(`GROUP BY class, race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"class", "race", "is_bot", "count"}).
+			AddRow(1, 1, 0, 9).
+			AddRow(1, 3, 0, 3).
+			AddRow(6, 5, 0, 4).
+			AddRow(11, 6, 0, 2))
+	db.characters.ExpectQuery(`WHERE level = 80`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "COUNT(*)"}).
+			AddRow(1, 10).
+			AddRow(2, 7))
+	db.characters.ExpectQuery(`SELECT COUNT(DISTINCT guid) FROM character_banned WHERE active = 1`).WillReturnRows(count(1))
+
+	assertGolden(t, "players", conns, "players")
+}
+
+func TestPlayersCollectorWithoutBotTables(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.addPlayerbots(t, conns)
+	db.playerbots.ExpectQuery(`SELECT DISTINCT bot FROM playerbots_random_bots`).
+		WillReturnError(&mysql.MySQLError{Number: 1146, Message: "Table 'acore_playerbots.playerbots_random_bots' doesn't exist"})
+
+	// Every character counts as a player
+	db.characters.ExpectQuery(`0 AS is_bot, COUNT(*) as count FROM characters WHERE online = 1 AND 1 GROUP BY race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).AddRow(1, 0, 4))
+	db.characters.ExpectQuery(`0 AS is_bot, COUNT(*) as count FROM characters WHERE (deleteDate IS NULL OR deleteDate = 0) AND 1 GROUP BY race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).AddRow(1, 0, 40))
+	db.characters.ExpectQuery(`GROUP BY level, race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"level", "race", "is_bot", "count"}).AddRow(80, 1, 0, 12))
+	db.characters.ExpectQuery(`GROUP BY class, race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"class", "race", "is_bot", "count"}).AddRow(1, 1, 0, 12))
+	db.characters.ExpectQuery(`WHERE level = 80`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "COUNT(*)"}).AddRow(1, 10))
+	db.characters.ExpectQuery(`SELECT COUNT(DISTINCT guid) FROM character_banned WHERE active = 1`).WillReturnRows(count(1))
+
+	assertGolden(t, "players", conns, "players_no_bot_tables")
+}
+
+func TestPlayersCollectorPlayerbotsDown(t *testing.T) {
+	conns, db := newTestConnections(t)
+	conns.Realms[0].PlayerbotsDown = true
+
+	// Only the metrics not labelled by is_bot are sent, and the run fails so
+	// that collector_success shows the others are missing
+	db.characters.ExpectQuery(`WHERE level = 80`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "COUNT(*)"}).AddRow(1, 10))
+	db.characters.ExpectQuery(`SELECT COUNT(DISTINCT guid) FROM character_banned WHERE active = 1`).WillReturnRows(count(1))
+
+	n, err := updateMetrics("players", conns)
+	if !errors.Is(err, database.ErrUnavailable) {
+		t.Errorf("got error %v, want ErrUnavailable", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d metrics, want the max level and banned characters", n)
+	}
+}
+
+func TestPlayersCollectorBots(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.addPlayerbots(t, conns)
+	db.playerbots.ExpectQuery(`SELECT DISTINCT bot FROM playerbots_random_bots`).
+		WillReturnRows(sqlmock.NewRows([]string{"bot"}).AddRow(101).AddRow(102))
+	db.playerbots.ExpectQuery(`SELECT account_id FROM playerbots_account_type WHERE account_type <> 0`).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id"}).AddRow(7))
+
+	isBot := `(guid IN (101, 102) OR account IN (7)) AS is_bot`
+	db.characters.ExpectQuery(isBot + `, COUNT(*) as count FROM characters WHERE online = 1 AND 1 GROUP BY race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).
+			AddRow(1, 0, 3).
+			AddRow(1, 1, 20).
+			AddRow(2, 1, 15))
+	db.characters.ExpectQuery(isBot).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).
+			AddRow(1, 0, 10).
+			AddRow(1, 1, 200).
+			AddRow(2, 1, 150))
+	db.characters.ExpectQuery(`GROUP BY level, race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"level", "race", "is_bot", "count"}).
+			AddRow(80, 1, 0, 2).
+			AddRow(80, 1, 1, 40))
+	db.characters.ExpectQuery(`GROUP BY class, race, is_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"class", "race", "is_bot", "count"}).
+			AddRow(1, 1, 0, 2).
+			AddRow(1, 1, 1, 40))
+	db.characters.ExpectQuery(`WHERE level = 80`).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "COUNT(*)"}).AddRow(1, 42))
+	db.characters.ExpectQuery(`SELECT COUNT(DISTINCT guid) FROM character_banned WHERE active = 1`).WillReturnRows(count(0))
+
+	assertGolden(t, "players", conns, "players_bots")
+}
+
+func TestPlayersCollectorExclusions(t *testing.T) {
+	cfg := testConfig()
+	cfg.Exclusions = config.ExclusionConfig{
+		NamePatterns: []string{"^Test", "(?i)gm$"},
+		Accounts:     []int{3, 1},
+		MinGMLevel:   2,
+		MinAge:       24 * time.Hour,
+	}
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`SELECT DISTINCT id FROM account_access WHERE gmlevel >= ? AND RealmID IN (-1, ?)`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(1))
+
+	included := `AND (name NOT REGEXP ? AND name NOT REGEXP ? AND account NOT IN (1, 3, 5) AND (creation_date IS NULL OR creation_date < DATE_SUB(NOW(), INTERVAL ? SECOND)))`
+	args := []driver.Value{"^Test", "(?i)gm$", int64(86400)}
+	db.characters.ExpectQuery(`WHERE online = 1 ` + included + ` GROUP BY race, is_bot`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).AddRow(1, 0, 4))
+	db.characters.ExpectQuery(`WHERE (deleteDate IS NULL OR deleteDate = 0) ` + included + ` GROUP BY race, is_bot`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "is_bot", "count"}).AddRow(1, 0, 40))
+	db.characters.ExpectQuery(included + ` GROUP BY level, race, is_bot`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"level", "race", "is_bot", "count"}).AddRow(80, 1, 0, 12))
+	db.characters.ExpectQuery(included + ` GROUP BY class, race, is_bot`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"class", "race", "is_bot", "count"}).AddRow(1, 1, 0, 12))
+	db.characters.ExpectQuery(`WHERE level = 80 AND (deleteDate IS NULL OR deleteDate = 0) ` + included).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"race", "COUNT(*)"}).AddRow(1, 10))
+	db.characters.ExpectQuery(`SELECT COUNT(DISTINCT guid) FROM character_banned WHERE active = 1`).WillReturnRows(count(1))
+
+	assertGoldenWithConfig(t, cfg, "players", conns, "players_exclusions")
+}
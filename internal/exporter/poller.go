@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Start begins refreshing every collector in the background on its own
+// interval when polling is enabled. The pollers stop when ctx is cancelled.
+// Without polling Start does nothing and collectors run on each scrape.
+func (e *Exporter) Start(ctx context.Context) {
+	if !e.scrape.Poll {
+		return
+	}
+	for _, c := range e.collectors {
+		interval := e.scrape.IntervalFor(c.Name())
+		slog.Info("Polling collector", "collector", c.Name(), "interval", interval)
+		e.pollers.Add(1)
+		go e.poll(ctx, c, interval)
+	}
+}
+
+// poll refreshes the snapshot of a collector immediately and then every interval
+func (e *Exporter) poll(ctx context.Context, c Collector, interval time.Duration) {
+	defer e.pollers.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.update(ctx, c)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// waitForRun waits until the named collector has run once
+func waitForRun(t *testing.T, e *Exporter, name string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		e.mu.Lock()
+		_, ran := e.states[name]
+		e.mu.Unlock()
+		if ran {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("collector %s did not run", name)
+}
+
+func TestPollingServesSnapshot(t *testing.T) {
+	conns, db := newTestConnections(t)
+	expectGuild(db)
+	cfg := testExporterConfig()
+	cfg.Scrape.Poll = true
+	pool := &fakePool{conns: conns}
+	e := newTestExporter(t, cfg, pool, "guild")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.Start(ctx)
+	waitForRun(t, e, "guild")
+	cancel()
+	e.Close()
+
+	// Scrapes serve the snapshot of the single poll without querying again
+	snapshot := `
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="guild"} 1
+# HELP wow_guild_count Number of guilds
+# TYPE wow_guild_count gauge
+wow_guild_count{realm="Azeroth"} 8
+`
+	for range 2 {
+		assertMetrics(t, e, snapshot, "wow_exporter_collector_success", "wow_guild_count")
+	}
+
+	// A failed refresh keeps serving the previous snapshot
+	db.characters.ExpectQuery(`SELECT COUNT(*) FROM guild`).WillReturnError(errors.New("connection reset"))
+	e.update(context.Background(), e.collectors[0])
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_success Whether the last run of a collector succeeded (1) or failed (0)
+# TYPE wow_exporter_collector_success gauge
+wow_exporter_collector_success{collector="guild"} 0
+# HELP wow_guild_count Number of guilds
+# TYPE wow_guild_count gauge
+wow_guild_count{realm="Azeroth"} 8
+`, "wow_exporter_collector_success", "wow_guild_count")
+
+	// A disabled collector drops its snapshot
+	pool.missing = map[string]bool{"guild": true}
+	e.update(context.Background(), e.collectors[0])
+	assertMetrics(t, e, `
+# HELP wow_exporter_collector_enabled Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason
+# TYPE wow_exporter_collector_enabled gauge
+wow_exporter_collector_enabled{collector="guild",realm="",reason="characters lacks table guild"} 0
+`, "wow_exporter_collector_enabled")
+	if n := testutil.CollectAndCount(e, "wow_guild_count"); n != 0 {
+		t.Errorf("got %d series of a disabled collector, want none", n)
+	}
+}
+
+func TestPollingServesNothingBeforeFirstRun(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	cfg := testExporterConfig()
+	cfg.Scrape.Poll = true
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "guild")
+
+	if n := testutil.CollectAndCount(e, "wow_exporter_collector_enabled", "wow_exporter_collector_success", "wow_guild_count"); n != 0 {
+		t.Errorf("got %d series before the first poll, want none", n)
+	}
+}
+
+func TestStartWithoutPolling(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	e := newTestExporter(t, testExporterConfig(), &fakePool{conns: conns}, "guild")
+
+	e.Start(context.Background())
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.states) != 0 {
+		t.Errorf("collectors ran without polling: %v", e.states)
+	}
+}
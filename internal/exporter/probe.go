@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+// ProbeHandler returns an http.Handler serving the metrics of the target
+// named by the target query parameter, in the style of the blackbox
+// exporter. Like Handler it honours the scrape timeout header and collect[]
+// parameters. Targets always run their collectors during the probe.
+func (e *Exporter) ProbeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("target")
+		if name == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		if _, exists := e.cfg.Targets[name]; !exists {
+			http.Error(w, fmt.Sprintf("unknown target %q", name), http.StatusBadRequest)
+			return
+		}
+
+		probe, err := e.probe(name)
+		if err != nil {
+			slog.Error("Error opening target", "target", name, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		collectors := probe.collectors
+		if names := r.URL.Query()["collect[]"]; len(names) > 0 {
+			if collectors, err = probe.filterCollectors(names); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := scrapeContext(r, e.scrape.TimeoutOffset)
+		defer cancel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(&scrape{exporter: probe, ctx: ctx, collectors: collectors})
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// probe returns the exporter of the named target, opening its databases on
+// first use. Later probes reuse its connection pools. The databases are
+// opened without holding probesMu, so a slow target does not hold up probes
+// of the others; when two probes open the same target at once, the first to
+// finish is kept.
+func (e *Exporter) probe(name string) (*Exporter, error) {
+	e.probesMu.Lock()
+	probe, exists := e.probes[name]
+	e.probesMu.Unlock()
+	if exists {
+		return probe, nil
+	}
+
+	pool, err := database.NewPool(e.cfg.Targets[name])
+	if err != nil {
+		return nil, err
+	}
+	probe, err = NewExporter(pool, e.cfg, e.collectorNames)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	probe.scrape.Poll = false
+
+	e.probesMu.Lock()
+	defer e.probesMu.Unlock()
+	if e.closed {
+		probe.Close()
+		return nil, errExporterClosed
+	}
+	if existing, exists := e.probes[name]; exists {
+		probe.Close()
+		return existing, nil
+	}
+	e.probes[name] = probe
+	slog.Info("Opened databases of target", "target", name)
+	return probe, nil
+}
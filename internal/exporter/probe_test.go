@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+// unreachableTarget returns the settings of a target whose databases refuse
+// every connection. Its realm is named in the settings, as its name cannot
+// be looked up.
+func unreachableTarget() config.DatabaseConfig {
+	const dsn = "exporter:secret@tcp(127.0.0.1:1)/"
+	return config.DatabaseConfig{
+		AuthDSN: dsn + "acore_auth",
+		Realms: []config.RealmConfig{{
+			ID:            1,
+			Name:          "Outland",
+			CharactersDSN: dsn + "acore_characters",
+			WorldDSN:      dsn + "acore_world",
+		}},
+		SchemaInterval: time.Minute,
+	}
+}
+
+func TestProbeHandlerRejectsMissingOrUnknownTarget(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	cfg := testExporterConfig()
+	cfg.Targets = map[string]config.DatabaseConfig{"community-b": unreachableTarget()}
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "guild")
+
+	for target, want := range map[string]string{
+		"/probe":                    "target parameter is missing",
+		"/probe?target=community-c": `unknown target "community-c"`,
+	} {
+		w := get(e.ProbeHandler(), target, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", target, w.Code, http.StatusBadRequest)
+		}
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%s: got body %q, want %q", target, w.Body, want)
+		}
+	}
+}
+
+func TestProbeHandlerReportsUnavailableTarget(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	cfg := testExporterConfig()
+	cfg.Targets = map[string]config.DatabaseConfig{"community-b": unreachableTarget()}
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "guild")
+	defer e.Close()
+
+	w := get(e.ProbeHandler(), "/probe?target=community-b&collect[]=guild", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`wow_database_up{database="auth",realm=""} 0`,
+		`wow_database_up{database="characters",realm="Outland"} 0`,
+		`wow_exporter_collector_success{collector="guild"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("%s is missing:\n%s", want, body)
+		}
+	}
+
+	w = get(e.ProbeHandler(), "/probe?target=community-b&collect[]=server", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for a collector that is not enabled, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestProbeReusesTarget(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	cfg := testExporterConfig()
+	cfg.Targets = map[string]config.DatabaseConfig{"community-b": unreachableTarget()}
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "guild")
+	defer e.Close()
+
+	first, err := e.probe("community-b")
+	if err != nil {
+		t.Fatalf("opening target: %v", err)
+	}
+	if first.scrape.Poll {
+		t.Error("the target polls its collectors")
+	}
+	second, err := e.probe("community-b")
+	if err != nil {
+		t.Fatalf("opening target again: %v", err)
+	}
+	if first != second {
+		t.Error("the target was opened twice")
+	}
+}
+
+func TestProbeAfterClose(t *testing.T) {
+	conns, _ := newTestConnections(t)
+	cfg := testExporterConfig()
+	cfg.Targets = map[string]config.DatabaseConfig{"community-b": unreachableTarget()}
+	e := newTestExporter(t, cfg, &fakePool{conns: conns}, "guild")
+
+	e.Close()
+	if _, err := e.probe("community-b"); !errors.Is(err, errExporterClosed) {
+		t.Errorf("got error %v probing a closed exporter, want %v", err, errExporterClosed)
+	}
+}
@@ -0,0 +1,178 @@
+package exporter
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+// Collector gathers the metrics of one domain from the AzerothCore databases
+type Collector interface {
+	// Name returns the collector name used in self-metric labels
+	Name() string
+	// Databases returns the databases the collector reads. The collector is
+	// skipped while they are unavailable, and realms with one of them down
+	// are left out of its connections.
+	Databases() []string
+	// Requirements returns the tables and columns the collector reads.
+	// Realms whose databases lack one of them are left out of its
+	// connections, and the collector is disabled while the auth database or
+	// every realm lacks one.
+	Requirements() []database.Requirement
+	// Describe sends the descriptors of every metric the collector can emit
+	Describe(ch chan<- *prometheus.Desc)
+	// Update queries the databases and sends the current metric values
+	Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error
+}
+
+// collectorFactories holds the constructors of all registered collectors by name
+var collectorFactories = make(map[string]func(cfg *config.Config) Collector)
+
+// registerCollector makes a collector available to the exporter. Each
+// collector calls it from the init function of its own file. The factory
+// receives the exporter configuration.
+func registerCollector(name string, factory func(cfg *config.Config) Collector) {
+	if _, exists := collectorFactories[name]; exists {
+		panic(fmt.Sprintf("collector %q registered twice", name))
+	}
+	collectorFactories[name] = factory
+}
+
+// CollectorNames returns the names of all registered collectors in sorted order
+func CollectorNames() []string {
+	names := make([]string, 0, len(collectorFactories))
+	for name := range collectorFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetricNames returns the names of the metrics of all registered collectors
+// that series limits can be configured for, in sorted order
+func MetricNames(cfg *config.Config) []string {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		for _, name := range CollectorNames() {
+			collectorFactories[name](cfg).Describe(descs)
+		}
+		close(descs)
+	}()
+
+	var names []string
+	for desc := range descs {
+		if limited, ok := limitedDescs.Load(desc); ok && !slices.Contains(names, limited.(limitedDesc).name) {
+			names = append(names, limited.(limitedDesc).name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCollectors instantiates the named collectors configured by cfg
+func newCollectors(names []string, cfg *config.Config) ([]Collector, error) {
+	if err := validateCollectorNames(names); err != nil {
+		return nil, err
+	}
+	collectors := make([]Collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, collectorFactories[name](cfg))
+	}
+	return collectors, nil
+}
+
+// collectorSwitch records an explicit command line choice for one collector
+type collectorSwitch struct {
+	set     bool
+	enabled bool
+}
+
+// switchFlag is the flag.Value behind --collector.<name> and, when inverted,
+// --no-collector.<name>
+type switchFlag struct {
+	state  *collectorSwitch
+	invert bool
+}
+
+func (f switchFlag) String() string {
+	if f.state == nil || !f.state.set {
+		return ""
+	}
+	return strconv.FormatBool(f.state.enabled != f.invert)
+}
+
+func (f switchFlag) Set(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	f.state.set = true
+	f.state.enabled = enabled != f.invert
+	return nil
+}
+
+func (f switchFlag) IsBoolFlag() bool { return true }
+
+// CollectorFlags holds the command line switches of every registered collector
+type CollectorFlags struct {
+	switches map[string]*collectorSwitch
+}
+
+// RegisterCollectorFlags adds --collector.<name> and --no-collector.<name>
+// flags for every registered collector to fs
+func RegisterCollectorFlags(fs *flag.FlagSet) *CollectorFlags {
+	f := &CollectorFlags{switches: make(map[string]*collectorSwitch)}
+	for _, name := range CollectorNames() {
+		state := &collectorSwitch{}
+		f.switches[name] = state
+		fs.Var(switchFlag{state: state}, "collector."+name, fmt.Sprintf("Enable the %s collector", name))
+		fs.Var(switchFlag{state: state, invert: true}, "no-collector."+name, fmt.Sprintf("Disable the %s collector", name))
+	}
+	return f
+}
+
+// Enabled resolves the collectors to run. The configured lists are applied
+// first: a non-empty enabled list restricts the exporter to those
+// collectors, and disabled collectors are removed. Switches given on the
+// command line override both.
+func (f *CollectorFlags) Enabled(cfg config.CollectorsConfig) ([]string, error) {
+	if err := validateCollectorNames(cfg.Enabled); err != nil {
+		return nil, err
+	}
+	if err := validateCollectorNames(cfg.Disabled); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range CollectorNames() {
+		enabled := len(cfg.Enabled) == 0 || slices.Contains(cfg.Enabled, name)
+		if slices.Contains(cfg.Disabled, name) {
+			enabled = false
+		}
+		if state := f.switches[name]; state != nil && state.set {
+			enabled = state.enabled
+		}
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// validateCollectorNames returns an error naming the first unregistered collector
+func validateCollectorNames(names []string) error {
+	for _, name := range names {
+		if _, exists := collectorFactories[name]; !exists {
+			return fmt.Errorf("unknown collector %q, available collectors: %s", name, strings.Join(CollectorNames(), ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"flag"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+func TestMetricNames(t *testing.T) {
+	names := MetricNames(testConfig())
+	if !slices.IsSorted(names) {
+		t.Errorf("metric names are not sorted: %v", names)
+	}
+	for _, name := range []string{"wow_online_players_by_level", "wow_network_activity_by_ip", "wow_active_battleground_total"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("metric %s is missing", name)
+		}
+	}
+	if slices.Contains(names, "wow_exporter_collector_success") {
+		t.Error("exporter self-metrics have no series limit and must not be listed")
+	}
+}
+
+func TestExampleConfigNames(t *testing.T) {
+	cfg, err := config.Load("../../config.example.yml")
+	if err != nil {
+		t.Fatalf("loading example config: %v", err)
+	}
+	if err := cfg.ValidateNames(CollectorNames(), MetricNames(cfg)); err != nil {
+		t.Errorf("example config names unknown collectors or metrics: %v", err)
+	}
+}
+
+func TestCollectorFlagsEnabled(t *testing.T) {
+	all := CollectorNames()
+	without := func(names ...string) []string {
+		return slices.DeleteFunc(slices.Clone(all), func(name string) bool { return slices.Contains(names, name) })
+	}
+	tests := []struct {
+		name string
+		cfg  config.CollectorsConfig
+		args []string
+		want []string
+	}{
+		{name: "defaults", want: all},
+		{name: "enabled list", cfg: config.CollectorsConfig{Enabled: []string{"server", "guild"}}, want: []string{"guild", "server"}},
+		{name: "disabled list", cfg: config.CollectorsConfig{Disabled: []string{"chat", "money"}}, want: without("chat", "money")},
+		{name: "disabled wins over enabled", cfg: config.CollectorsConfig{Enabled: []string{"guild", "server"}, Disabled: []string{"server"}}, want: []string{"guild"}},
+		{name: "flag enables", cfg: config.CollectorsConfig{Enabled: []string{"guild"}}, args: []string{"--collector.server"}, want: []string{"guild", "server"}},
+		{name: "flag overrides disabled list", cfg: config.CollectorsConfig{Disabled: []string{"chat"}}, args: []string{"--collector.chat"}, want: all},
+		{name: "flag disables", args: []string{"--no-collector.chat"}, want: without("chat")},
+		{name: "flag set to false", args: []string{"--collector.chat=false"}, want: without("chat")},
+		{name: "negated flag set to false", cfg: config.CollectorsConfig{Enabled: []string{"guild"}}, args: []string{"--no-collector.server=false"}, want: []string{"guild", "server"}},
+		{name: "last flag wins", args: []string{"--no-collector.chat", "--collector.chat"}, want: all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("exporter", flag.ContinueOnError)
+			flags := RegisterCollectorFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("parsing flags: %v", err)
+			}
+			got, err := flags.Enabled(tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorFlagsEnabledRejectsUnknownCollector(t *testing.T) {
+	flags := RegisterCollectorFlags(flag.NewFlagSet("exporter", flag.ContinueOnError))
+	for _, cfg := range []config.CollectorsConfig{
+		{Enabled: []string{"guilds"}},
+		{Disabled: []string{"guilds"}},
+	} {
+		if _, err := flags.Enabled(cfg); err == nil || !strings.Contains(err.Error(), `unknown collector "guilds"`) {
+			t.Errorf("%+v: got error %v, want one naming the unknown collector", cfg, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/database"
+)
+
+func init() {
+	registerCollector("server", newServerCollector)
+}
+
+// serverCollector exports worldserver uptime metrics from the auth database
+type serverCollector struct {
+	uptime      *prometheus.Desc
+	maxPlayers  *prometheus.Desc
+	lastRestart *prometheus.Desc
+}
+
+func newServerCollector(cfg *config.Config) Collector {
+	return &serverCollector{
+		uptime: newValueDesc(
+			"wow_server_uptime_seconds",
+			"Server uptime in seconds",
+			[]string{"realm"},
+		),
+		maxPlayers: newValueDesc(
+			"wow_server_max_players",
+			"Maximum number of players recorded",
+			[]string{"realm"},
+		),
+		lastRestart: newValueDesc(
+			"wow_server_last_restart_timestamp",
+			"Timestamp of the last server restart (unix time)",
+			[]string{"realm"},
+		),
+	}
+}
+
+func (c *serverCollector) Name() string        { return "server" }
+func (c *serverCollector) Databases() []string { return []string{database.AuthDatabase} }
+
+func (c *serverCollector) Requirements() []database.Requirement {
+	return []database.Requirement{
+		{Database: database.AuthDatabase, Table: "uptime", Columns: []string{"realmid", "starttime", "uptime", "maxplayers"}},
+	}
+}
+
+func (c *serverCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.uptime
+	ch <- c.maxPlayers
+	ch <- c.lastRestart
+}
+
+func (c *serverCollector) Update(ctx context.Context, conns *database.Connections, ch chan<- prometheus.Metric) error {
+	return forEachRealm(conns, func(realm *database.Realm) error {
+		return c.updateRealm(ctx, conns, realm, ch)
+	})
+}
+
+func (c *serverCollector) updateRealm(ctx context.Context, conns *database.Connections, realm *database.Realm, ch chan<- prometheus.Metric) error {
+	// Query for server uptime, max players and start time of the latest run (auth database)
+	query := `
+		SELECT 
+			starttime,
+			uptime,
+			maxplayers
+		FROM uptime 
+		WHERE realmid = ? 
+		ORDER BY starttime DESC 
+		LIMIT 1
+	`
+	var starttime int64
+	var uptime, maxPlayers int
+	err := conns.Auth.QueryRowContext(ctx, query, realm.ID).Scan(&starttime, &uptime, &maxPlayers)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return queryFailed(database.AuthDatabase, "server_uptime", err)
+	}
+
+	gauge(ch, c.uptime, float64(uptime), realm.Name)
+	gauge(ch, c.maxPlayers, float64(maxPlayers), realm.Name)
+	gauge(ch, c.lastRestart, float64(starttime), realm.Name)
+
+	return nil
+}
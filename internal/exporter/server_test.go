@@ -0,0 +1,25 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestServerCollector(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`FROM uptime WHERE realmid = ? ORDER BY starttime DESC LIMIT 1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"starttime", "uptime", "maxplayers"}).AddRow(1700000000, 86400, 42))
+
+	assertGolden(t, "server", conns, "server")
+}
+
+func TestServerCollectorWithoutUptime(t *testing.T) {
+	conns, db := newTestConnections(t)
+	db.auth.ExpectQuery(`FROM uptime WHERE realmid = ?`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"starttime", "uptime", "maxplayers"}))
+
+	assertGolden(t, "server", conns, "server_empty")
+}
@@ -1,466 +1,137 @@
 package metrics
 
 import (
+	"runtime"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// Player metrics
+// Exporter self-metrics
 var (
-	PlayersOnline = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_players_online",
-			Help: "Number of players currently online",
-		},
-		[]string{"faction"},
+	CollectorSuccess = prometheus.NewDesc(
+		"wow_exporter_collector_success",
+		"Whether the last run of a collector succeeded (1) or failed (0)",
+		[]string{"collector"}, nil,
 	)
 
-	PlayersTotal = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_players_total",
-			Help: "Total number of players",
-		},
-		[]string{"faction"},
+	CollectorDuration = prometheus.NewDesc(
+		"wow_exporter_collector_duration_seconds",
+		"Duration of the last run of a collector in seconds",
+		[]string{"collector"}, nil,
 	)
 
-	PlayersByLevel = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_players_by_level",
-			Help: "Number of players by level",
-		},
-		[]string{"level", "faction"},
+	CollectorLastSuccess = prometheus.NewDesc(
+		"wow_exporter_collector_last_success_timestamp_seconds",
+		"Unix time of the last successful run of a collector",
+		[]string{"collector"}, nil,
 	)
 
-	PlayersByClass = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_players_by_class",
-			Help: "Number of players by class",
-		},
-		[]string{"class", "faction"},
+	CollectorEnabled = prometheus.NewDesc(
+		"wow_exporter_collector_enabled",
+		"Whether a collector runs (1) or is disabled because its databases lack a table or column it needs (0), with the missing part as reason; realm names a single realm left out for that reason",
+		[]string{"collector", "realm", "reason"}, nil,
 	)
 
-	OnlinePlayersByLevel = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_online_players_by_level",
-			Help: "Online characters by name and account, value is the character's level",
-		},
-		[]string{"character_name", "account_name"},
+	DatabaseUp = prometheus.NewDesc(
+		"wow_database_up",
+		"Whether a database is reachable (1) or not (0); realm is empty for the auth database",
+		[]string{"database", "realm"}, nil,
 	)
 )
 
-// Mail metrics
+// Database connection pool metrics, from sql.DBStats
 var (
-	MailTotal = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_mail_total",
-			Help: "Total number of mail messages",
-		},
+	DBMaxOpenConnections = prometheus.NewDesc(
+		"wow_exporter_db_max_open_connections",
+		"Maximum number of open connections to the database",
+		[]string{"database", "realm"}, nil,
 	)
 
-	MailByFaction = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_mail_by_faction",
-			Help: "Number of mail messages by faction",
-		},
-		[]string{"faction"},
+	DBOpenConnections = prometheus.NewDesc(
+		"wow_exporter_db_open_connections",
+		"Number of established connections to the database, in use and idle",
+		[]string{"database", "realm"}, nil,
 	)
 
-	MailWithItems = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_mail_with_items",
-			Help: "Number of mail messages with items",
-		},
+	DBInUseConnections = prometheus.NewDesc(
+		"wow_exporter_db_in_use_connections",
+		"Number of connections to the database currently in use",
+		[]string{"database", "realm"}, nil,
 	)
-)
 
-// Account metrics
-var (
-	AccountsTotal = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_accounts_total",
-			Help: "Total number of accounts",
-		},
+	DBIdleConnections = prometheus.NewDesc(
+		"wow_exporter_db_idle_connections",
+		"Number of idle connections to the database",
+		[]string{"database", "realm"}, nil,
 	)
 
-	AccountsOnline = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_accounts_online",
-			Help: "Number of accounts currently online",
-		},
+	DBWaitCount = prometheus.NewDesc(
+		"wow_exporter_db_wait_count_total",
+		"Total number of connections waited for",
+		[]string{"database", "realm"}, nil,
 	)
 
-	AccountsBanned = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_accounts_banned",
-			Help: "Number of banned accounts",
-		},
+	DBWaitDuration = prometheus.NewDesc(
+		"wow_exporter_db_wait_duration_seconds_total",
+		"Total time blocked waiting for a new connection in seconds",
+		[]string{"database", "realm"}, nil,
 	)
-)
 
-// Server metrics
-var (
-	ServerUptime = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_server_uptime_seconds",
-			Help: "Server uptime in seconds",
-		},
+	DBMaxIdleClosed = prometheus.NewDesc(
+		"wow_exporter_db_max_idle_closed_total",
+		"Total number of connections closed due to the idle connection limit",
+		[]string{"database", "realm"}, nil,
 	)
 
-	ServerMaxPlayers = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_server_max_players",
-			Help: "Maximum number of players recorded",
-		},
+	DBMaxIdleTimeClosed = prometheus.NewDesc(
+		"wow_exporter_db_max_idle_time_closed_total",
+		"Total number of connections closed due to the maximum idle time",
+		[]string{"database", "realm"}, nil,
 	)
-)
 
-// Auction metrics
-var (
-	AuctionCount = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_auction_count",
-			Help: "Number of active auctions by house (faction)",
-		},
-		[]string{"house"},
-	)
-)
-
-// Guild metrics
-var (
-	GuildCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_guild_count",
-			Help: "Number of guilds",
-		},
+	DBMaxLifetimeClosed = prometheus.NewDesc(
+		"wow_exporter_db_max_lifetime_closed_total",
+		"Total number of connections closed due to the maximum connection lifetime",
+		[]string{"database", "realm"}, nil,
 	)
 )
 
-// Character metrics
-var (
-	MaxLevelCharCount = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_max_level_characters",
-			Help: "Number of max-level characters by faction",
+// NewScrapeErrors creates the counter of failed collector runs. Each exporter
+// owns its own counter so that separate exporters never share state.
+func NewScrapeErrors() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wow_exporter_scrape_errors_total",
+			Help: "Total number of collector runs that returned an error",
 		},
-		[]string{"faction"},
+		[]string{"collector"},
 	)
+}
 
-	UnreadMailCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_unread_mail_count",
-			Help: "Number of unread mail messages",
+// NewSeriesDropped creates the counter of series the series limits fold into
+// "other" or drop. Like the scrape error counter it is owned by one exporter.
+func NewSeriesDropped() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wow_exporter_series_dropped_total",
+			Help: "Total number of series over the series limit of their metric, folded into an \"other\" series when their values add up and dropped otherwise",
 		},
+		[]string{"metric"},
 	)
+}
 
-	GMAccountCount = prometheus.NewGauge(
+// NewBuildInfo creates the constant build information metric of the exporter
+func NewBuildInfo(version, revision string) prometheus.Collector {
+	return prometheus.NewGaugeFunc(
 		prometheus.GaugeOpts{
-			Name: "wow_gm_account_count",
-			Help: "Number of accounts with GM level",
+			Name: "wow_exporter_build_info",
+			Help: "A metric with a constant '1' value labeled by the version, revision and Go version the exporter was built from",
+			ConstLabels: prometheus.Labels{
+				"version":   version,
+				"revision":  revision,
+				"goversion": runtime.Version(),
+			},
 		},
+		func() float64 { return 1 },
 	)
-
-	LastServerRestart = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_server_last_restart_timestamp",
-			Help: "Timestamp of the last server restart (unix time)",
-		},
-	)
-
-	BannedCharCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_banned_characters",
-			Help: "Number of banned characters",
-		},
-	)
-)
-
-// Chat and activity metrics
-var (
-	ChannelCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_channel_count",
-			Help: "Number of chat channels",
-		},
-	)
-
-	ChannelBans = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_channel_bans",
-			Help: "Number of channel bans",
-		},
-	)
-
-	LogCountByType = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_log_count",
-			Help: "Number of log entries by type",
-		},
-		[]string{"type"},
-	)
-
-	GuildEventCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_guild_events",
-			Help: "Number of guild events",
-		},
-	)
-
-	MoneyLogCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_money_logs",
-			Help: "Number of money transaction logs",
-		},
-	)
-
-	EncounterLogCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_encounter_logs",
-			Help: "Number of encounter logs",
-		},
-	)
-
-	ArenaLogCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_arena_logs",
-			Help: "Number of arena fight logs",
-		},
-	)
-
-	IPActionLogCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_ip_action_logs",
-			Help: "Number of IP action logs",
-		},
-	)
-)
-
-// Instance and raid metrics
-var (
-	ActiveInstanceCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_active_instances",
-			Help: "Number of active instances",
-		},
-	)
-
-	InstancesByDifficulty = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_instances_by_difficulty",
-			Help: "Number of instances by difficulty",
-		},
-		[]string{"difficulty"},
-	)
-
-	CompletedEncounters = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_completed_encounters",
-			Help: "Number of completed encounters by instance",
-		},
-		[]string{"instance_id"},
-	)
-
-	InstanceResets = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_instance_resets",
-			Help: "Instance reset times by map and difficulty",
-		},
-		[]string{"map_id", "difficulty"},
-	)
-
-	CharactersInInstances = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_characters_in_instances",
-			Help: "Number of characters currently in instances",
-		},
-	)
-
-	LFGDataCount = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_lfg_data",
-			Help: "Number of LFG entries by state",
-		},
-		[]string{"state"},
-	)
-
-	LagReportsCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_lag_reports",
-			Help: "Number of lag reports",
-		},
-	)
-
-	InstanceSavesCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_instance_saves",
-			Help: "Number of saved instance states",
-		},
-	)
-)
-
-// Networking metrics
-var (
-	PlayerLatencyStats = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_player_latency",
-			Help: "Player latency statistics",
-		},
-		[]string{"stat"},
-	)
-
-	IPBannedCount = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_ip_banned_count",
-			Help: "Number of banned IP addresses",
-		},
-	)
-
-	IPActionLogsByType = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_ip_action_logs_by_type",
-			Help: "Number of IP action logs by type",
-		},
-		[]string{"type"},
-	)
-
-	LagReportsByType = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_lag_reports_by_type",
-			Help: "Number of lag reports by type",
-		},
-		[]string{"lag_type"},
-	)
-
-	AverageLatency = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_average_latency_ms",
-			Help: "Average player latency in milliseconds",
-		},
-	)
-
-	HighLatencyPlayers = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_high_latency_players",
-			Help: "Number of players with high latency (>200ms)",
-		},
-	)
-
-	NetworkActivityByIP = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_network_activity_by_ip",
-			Help: "Network activity by IP address (top 10)",
-		},
-		[]string{"ip"},
-	)
-)
-
-// Battleground metrics
-var (
-	BattlegroundDeserters = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_deserters",
-			Help: "Number of battleground deserters",
-		},
-	)
-
-	BattlegroundDesertersByType = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_deserters_by_type",
-			Help: "Number of battleground deserters by type",
-		},
-		[]string{"desertion_type"},
-	)
-
-	RandomBattlegroundQueue = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_random_battleground_queue",
-			Help: "Number of players in random battleground queue",
-		},
-	)
-
-	BattlegroundStats = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_stats",
-			Help: "Battleground statistics",
-		},
-		[]string{"stat"},
-	)
-
-	BattlegroundsByType = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battlegrounds_by_type",
-			Help: "Number of battlegrounds by type",
-		},
-		[]string{"battleground_type"},
-	)
-
-	BattlegroundsByBracket = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battlegrounds_by_bracket",
-			Help: "Number of battlegrounds by bracket",
-		},
-		[]string{"bracket"},
-	)
-
-	BattlegroundWinsByFaction = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_wins_by_faction",
-			Help: "Number of battleground wins by faction",
-		},
-		[]string{"faction"},
-	)
-
-	BattlegroundPlayerStats = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_player_stats",
-			Help: "Battleground player statistics",
-		},
-		[]string{"stat"},
-	)
-
-	BattlegroundTemplates = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_templates",
-			Help: "Battleground template information",
-		},
-		[]string{"template_id", "script_name"},
-	)
-
-	BattlegroundTemplateDetails = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_battleground_template_details",
-			Help: "Detailed battleground template information",
-		},
-		[]string{"template_id", "name", "min_level", "max_level", "min_players", "max_players"},
-	)
-
-	RecentBattlegrounds = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_recent_battlegrounds",
-			Help: "Recent battleground activity",
-		},
-		[]string{"time_period"},
-	)
-
-	// Active battleground metrics
-	ActiveBattlegrounds = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_active_battlegrounds",
-			Help: "Number of active battlegrounds by type",
-		},
-		[]string{"battleground_name", "map_id"},
-	)
-
-	ActiveBattlegroundPlayers = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wow_active_battleground_players",
-			Help: "Number of players currently in battlegrounds by type",
-		},
-		[]string{"battleground_name", "map_id", "faction", "instance_id"},
-	)
-
-	ActiveBattlegroundTotal = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "wow_active_battleground_total",
-			Help: "Total number of players currently in battlegrounds",
-		},
-	)
-)
+}
@@ -2,6 +2,10 @@ package constants
 
 import "fmt"
 
+// The maps below describe the races, classes and battlegrounds of
+// WotLK 3.3.5a. They are the fallback for names loaded from the DBC tables of
+// the world database, see package dbc.
+
 // WoW race to faction mapping
 var RaceToFaction = map[int]string{
 	1:  "Alliance", // Human
@@ -12,13 +16,8 @@ var RaceToFaction = map[int]string{
 	6:  "Horde",    // Tauren
 	7:  "Alliance", // Gnome
 	8:  "Horde",    // Troll
-	9:  "Horde",    // Goblin
 	10: "Horde",    // Blood Elf
 	11: "Alliance", // Draenei
-	22: "Horde",    // Worgen
-	24: "Alliance", // Pandaren (Neutral)
-	25: "Alliance", // Pandaren (Alliance)
-	26: "Horde",    // Pandaren (Horde)
 }
 
 // WoW race names
@@ -31,13 +30,8 @@ var RaceNames = map[int]string{
 	6:  "Tauren",
 	7:  "Gnome",
 	8:  "Troll",
-	9:  "Goblin",
 	10: "Blood Elf",
 	11: "Draenei",
-	22: "Worgen",
-	24: "Pandaren (Neutral)",
-	25: "Pandaren (Alliance)",
-	26: "Pandaren (Horde)",
 }
 
 // WoW class names
@@ -51,9 +45,35 @@ var ClassNames = map[int]string{
 	7:  "Shaman",
 	8:  "Mage",
 	9:  "Warlock",
-	10: "Monk",
 	11: "Druid",
-	12: "Demon Hunter",
+}
+
+// Battleground type names by BattlemasterList ID, as stored in
+// pvpstats_battlegrounds.type
+var BattlegroundTypeNames = map[int]string{
+	1:  "Alterac Valley",
+	2:  "Warsong Gulch",
+	3:  "Arathi Basin",
+	4:  "Nagrand Arena",
+	5:  "Blade's Edge Arena",
+	6:  "All Arenas",
+	7:  "Eye of the Storm",
+	8:  "Ruins of Lordaeron",
+	9:  "Strand of the Ancients",
+	10: "Dalaran Sewers",
+	11: "The Ring of Valor",
+	30: "Isle of Conquest",
+	32: "Random Battleground",
+}
+
+// Battleground map names by map ID
+var BattlegroundMaps = map[int]string{
+	30:  "Alterac Valley",
+	489: "Warsong Gulch",
+	529: "Arathi Basin",
+	566: "Eye of the Storm",
+	607: "Strand of the Ancients",
+	628: "Isle of Conquest",
 }
 
 // Helper functions for readable names
@@ -121,26 +141,6 @@ func GetLagTypeName(lagType int) string {
 	return fmt.Sprintf("Unknown_%d", lagType)
 }
 
-func GetBattlegroundTypeName(bgType int) string {
-	bgTypeNames := map[int]string{
-		1:  "Alterac Valley",
-		2:  "Warsong Gulch",
-		3:  "Arathi Basin",
-		4:  "Eye of the Storm",
-		5:  "Strand of the Ancients",
-		6:  "Isle of Conquest",
-		7:  "Twin Peaks",
-		8:  "Battle for Gilneas",
-		9:  "Temple of Kotmogu",
-		10: "Silvershard Mines",
-		11: "Deepwind Gorge",
-	}
-	if name, exists := bgTypeNames[bgType]; exists {
-		return name
-	}
-	return fmt.Sprintf("Unknown_%d", bgType)
-}
-
 func GetDesertionTypeName(desertionType int) string {
 	desertionTypeNames := map[int]string{
 		0: "Leave",
@@ -153,3 +153,18 @@ func GetDesertionTypeName(desertionType int) string {
 	}
 	return fmt.Sprintf("Unknown_%d", desertionType)
 }
+
+func GetMoneyTypeName(moneyType int) string {
+	moneyTypeNames := map[int]string{
+		1: "COD",
+		2: "Auction",
+		3: "Guild_Bank_Deposit",
+		4: "Guild_Bank_Withdraw",
+		5: "Mail",
+		6: "Trade",
+	}
+	if name, exists := moneyTypeNames[moneyType]; exists {
+		return name
+	}
+	return fmt.Sprintf("Unknown_%d", moneyType)
+}
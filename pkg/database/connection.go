@@ -1,95 +1,62 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"log"
-	"strings"
+	"log/slog"
 
 	_ "github.com/go-sql-driver/mysql"
-)
-
-// Connections holds all database connections
-type Connections struct {
-	Characters *sql.DB
-	Auth       *sql.DB
-	World      *sql.DB
-}
-
-// NewConnections creates new database connections for all three databases
-func NewConnections(dsn string) (*Connections, error) {
-	// Create connection to characters database
-	charactersDB, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	// Test the characters connection
-	if err := charactersDB.Ping(); err != nil {
-		return nil, err
-	}
-
-	// Create connection to auth database by modifying the DSN
-	authDSN := strings.Replace(dsn, "/acore_characters?", "/acore_auth?", 1)
-	authDB, err := sql.Open("mysql", authDSN)
-	if err != nil {
-		closeWithLog(charactersDB, "characters")
-		return nil, err
-	}
 
-	// Test the auth connection
-	if err := authDB.Ping(); err != nil {
-		closeWithLog(charactersDB, "characters")
-		closeWithLog(authDB, "auth")
-		return nil, err
-	}
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
 
-	// Create connection to world database by modifying the DSN
-	worldDSN := strings.Replace(dsn, "/acore_characters?", "/acore_world?", 1)
-	worldDB, err := sql.Open("mysql", worldDSN)
-	if err != nil {
-		closeWithLog(charactersDB, "characters")
-		closeWithLog(authDB, "auth")
-		return nil, err
-	}
+// Database names used to declare which databases a collector reads
+const (
+	AuthDatabase       = "auth"
+	CharactersDatabase = "characters"
+	WorldDatabase      = "world"
+	PlayerbotsDatabase = "playerbots"
+)
 
-	// Test the world connection
-	if err := worldDB.Ping(); err != nil {
-		closeWithLog(charactersDB, "characters")
-		closeWithLog(authDB, "auth")
-		closeWithLog(worldDB, "world")
-		return nil, err
-	}
+// Querier runs queries against a database. *sql.DB implements it; tests
+// replace it with a fake.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	return &Connections{
-		Characters: charactersDB,
-		Auth:       authDB,
-		World:      worldDB,
-	}, nil
+// Connections holds the database connections available to a collector run
+type Connections struct {
+	Auth   Querier
+	Realms []*Realm
 }
 
-// Close closes all database connections
-func (c *Connections) Close() {
-	if c.Characters != nil {
-		closeWithLog(c.Characters, "characters")
-	}
-	if c.Auth != nil {
-		closeWithLog(c.Auth, "auth")
-	}
-	if c.World != nil {
-		closeWithLog(c.World, "world")
-	}
+// Realm holds the connections of a single realm. Realms share the auth
+// database but each has its own characters and world database, and
+// optionally a playerbots database.
+type Realm struct {
+	ID         int
+	Name       string
+	Characters Querier
+	World      Querier
+	// Playerbots is nil unless a playerbots database is configured and
+	// available; PlayerbotsDown is set when one is configured but down
+	Playerbots     Querier
+	PlayerbotsDown bool
+	// Names resolves the IDs of the realm's game data to names
+	Names *dbc.Names
 }
 
 // Helper functions for error handling
 func closeWithLog(db *sql.DB, name string) {
 	if err := db.Close(); err != nil {
-		log.Printf("Error closing %s database: %v", name, err)
+		slog.Error("Error closing database", "database", name, "err", err)
 	}
 }
 
 // CloseRowsWithLog closes rows with error logging
 func CloseRowsWithLog(rows *sql.Rows) {
 	if err := rows.Close(); err != nil {
-		log.Printf("Error closing rows: %v", err)
+		slog.Error("Error closing rows", "err", err)
 	}
 }
@@ -0,0 +1,422 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
+
+const (
+	// pingTimeout bounds a single availability check
+	pingTimeout = 5 * time.Second
+	// checkInterval is the time between checks of an available database
+	checkInterval = 15 * time.Second
+	// minBackoff and maxBackoff bound the time between reconnection attempts
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// ErrUnavailable is returned when a database a collector needs is down
+var ErrUnavailable = errors.New("database unavailable")
+
+// Pool owns the database connections of the auth database and every realm.
+// It keeps checking each database in the background, retrying unavailable
+// ones with exponential backoff, so the exporter keeps running while a
+// database is down.
+type Pool struct {
+	auth   *handle
+	realms []*realmHandles
+
+	// mu guards the realm names, which are looked up once auth is available
+	mu sync.Mutex
+
+	// schemaInterval is the time between reloads of the database schemas
+	schemaInterval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// handle is a single database connection pool, its availability and its
+// schema
+type handle struct {
+	name  string
+	realm *realmHandles
+	db    *sql.DB
+	up    atomic.Bool
+
+	// schema is nil until it is loaded; schemaLoaded is only used by the
+	// goroutine checking the database
+	schema       atomic.Pointer[schema]
+	schemaLoaded time.Time
+}
+
+// realmHandles holds the databases of one realm
+type realmHandles struct {
+	id int
+	// name is the configured or realmlist name of the realm, set once named
+	name       string
+	named      bool
+	characters *handle
+	world      *handle
+	playerbots *handle
+
+	// names holds the names loaded from the world database
+	names atomic.Pointer[dbc.Names]
+}
+
+// Status is the availability and pool statistics of one database
+type Status struct {
+	Database string
+	// Realm is empty for the auth database
+	Realm string
+	Up    bool
+	Stats sql.DBStats
+}
+
+// NewPool opens the databases configured by cfg, using the DSNs resolved by
+// config.Load, and checks them and loads their schema once before
+// returning. Unavailable databases do not cause an error; only an invalid
+// configuration does.
+func NewPool(cfg config.DatabaseConfig) (*Pool, error) {
+	p := &Pool{schemaInterval: cfg.SchemaInterval}
+
+	var err error
+	if p.auth, err = p.open(cfg, AuthDatabase, nil, cfg.AuthDSN); err != nil {
+		return nil, err
+	}
+	for _, realmConfig := range cfg.Realms {
+		realm := &realmHandles{id: realmConfig.ID, name: realmConfig.Name, named: realmConfig.Name != ""}
+		realm.names.Store(dbc.Default())
+		p.realms = append(p.realms, realm)
+
+		if realm.characters, err = p.open(cfg, CharactersDatabase, realm, realmConfig.CharactersDSN); err == nil {
+			realm.world, err = p.open(cfg, WorldDatabase, realm, realmConfig.WorldDSN)
+		}
+		if err == nil && realmConfig.PlayerbotsDSN != "" {
+			realm.playerbots, err = p.open(cfg, PlayerbotsDatabase, realm, realmConfig.PlayerbotsDSN)
+		}
+		if err != nil {
+			p.closeDatabases()
+			return nil, fmt.Errorf("realm %d: %w", realmConfig.ID, err)
+		}
+	}
+
+	// Check every database once so that the first scrape sees their state
+	var wg sync.WaitGroup
+	for _, h := range p.handles() {
+		wg.Add(1)
+		go func(h *handle) {
+			defer wg.Done()
+			if err := h.ping(context.Background()); err != nil {
+				slog.Warn("Database is unavailable", append(h.attrs(), "err", err)...)
+				return
+			}
+			h.loadSchema(context.Background())
+		}(h)
+	}
+	wg.Wait()
+	p.lookupRealmNames(context.Background())
+	for _, realm := range p.realms {
+		if realm.world.up.Load() {
+			realm.loadNames(context.Background())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for _, h := range p.handles() {
+		p.wg.Add(1)
+		go p.watch(ctx, h)
+	}
+
+	return p, nil
+}
+
+// open creates a connection pool applying the pool settings cfg holds for
+// the named database. The database is not contacted.
+func (p *Pool) open(cfg config.DatabaseConfig, name string, realm *realmHandles, dsn string) (*handle, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s database: %w", name, err)
+	}
+	pool := cfg.PoolFor(name)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	return &handle{name: name, realm: realm, db: db}, nil
+}
+
+// handles returns every database of the pool
+func (p *Pool) handles() []*handle {
+	var handles []*handle
+	if p.auth != nil {
+		handles = append(handles, p.auth)
+	}
+	for _, realm := range p.realms {
+		for _, h := range []*handle{realm.characters, realm.world, realm.playerbots} {
+			if h != nil {
+				handles = append(handles, h)
+			}
+		}
+	}
+	return handles
+}
+
+// watch checks a database until ctx is done. Available databases are
+// checked every checkInterval and their schema reloaded every
+// schemaInterval and whenever they come back; unavailable ones are retried
+// with exponential backoff.
+func (p *Pool) watch(ctx context.Context, h *handle) {
+	defer p.wg.Done()
+
+	backoff := minBackoff
+	wait := checkInterval
+	if !h.up.Load() {
+		wait = backoff
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := p.check(ctx, h); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		wait = checkInterval
+		backoff = minBackoff
+	}
+}
+
+// check checks a database once and returns the error of an unavailable one.
+// A database coming back has its schema reloaded, along with the game data
+// names for world. Realm names not known yet are looked up whenever auth is
+// available.
+func (p *Pool) check(ctx context.Context, h *handle) error {
+	wasUp := h.up.Load()
+	if err := h.ping(ctx); err != nil {
+		if wasUp && ctx.Err() == nil {
+			slog.Warn("Database went down", append(h.attrs(), "err", err)...)
+		}
+		return err
+	}
+
+	if !wasUp {
+		slog.Info("Database is available again", h.attrs()...)
+	}
+	if h == p.auth {
+		p.lookupRealmNames(ctx)
+	}
+	if !wasUp || time.Since(h.schemaLoaded) >= p.schemaInterval {
+		h.loadSchema(ctx)
+	}
+	if !wasUp && h.realm != nil && h == h.realm.world {
+		h.realm.loadNames(ctx)
+	}
+	return nil
+}
+
+// ping checks the database and records whether it is available
+func (h *handle) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	err := h.db.PingContext(ctx)
+	h.up.Store(err == nil)
+	return err
+}
+
+// attrs returns the log attributes identifying the database
+func (h *handle) attrs() []any {
+	if h.realm == nil {
+		return []any{"database", h.name}
+	}
+	return []any{"database", h.name, "realm_id", h.realm.id}
+}
+
+// String returns the database name, prefixed by the realm ID for realm databases
+func (h *handle) String() string {
+	if h.realm == nil {
+		return h.name
+	}
+	return fmt.Sprintf("realm %d %s", h.realm.id, h.name)
+}
+
+// lookupRealmNames looks up the names of the realms not named yet in the
+// realmlist. Realms missing from the realmlist are named by their ID; those
+// whose lookup fails stay unnamed until the next lookup.
+func (p *Pool) lookupRealmNames(ctx context.Context) {
+	if !p.auth.up.Load() {
+		return
+	}
+	for _, realm := range p.realms {
+		p.mu.Lock()
+		named := realm.named
+		p.mu.Unlock()
+		if named {
+			continue
+		}
+
+		var name string
+		err := p.auth.db.QueryRowContext(ctx, `SELECT name FROM realmlist WHERE id = ?`, realm.id).Scan(&name)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			slog.Warn("Realm is not in the realmlist, using its ID as name", "realm_id", realm.id)
+			name = strconv.Itoa(realm.id)
+		case err != nil:
+			slog.Warn("Could not look up realm name, leaving the realm out until it is known", "realm_id", realm.id, "err", err)
+			continue
+		}
+		p.mu.Lock()
+		realm.name = name
+		realm.named = true
+		p.mu.Unlock()
+	}
+}
+
+// loadNames loads the names of the realm's game data from its world
+// database. Names that cannot be loaded keep their built-in value.
+func (r *realmHandles) loadNames(ctx context.Context) {
+	names, err := dbc.Load(ctx, r.world.db, r.world.hasTable)
+	if err != nil {
+		slog.Warn("Could not load names from the world database, using built-in names", append(r.world.attrs(), "err", err)...)
+	}
+	r.names.Store(names)
+}
+
+// Connections returns the connections a collector reading the given
+// databases can use. Realms with one of those databases down are left out,
+// as are realms whose name is not known yet.
+// ErrUnavailable is returned when auth is needed but down, or when realm
+// databases are needed and no realm has them all available.
+func (p *Pool) Connections(databases ...string) (*Connections, error) {
+	if slices.Contains(databases, AuthDatabase) && !p.auth.up.Load() {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, p.auth)
+	}
+
+	needsRealm := false
+	var down []string
+	conns := &Connections{Auth: p.auth.db}
+	for _, realm := range p.realms {
+		available := true
+		for _, h := range []*handle{realm.characters, realm.world, realm.playerbots} {
+			// The playerbots database is optional, collectors check for it
+			if h == nil || !slices.Contains(databases, h.name) {
+				continue
+			}
+			needsRealm = true
+			if !h.up.Load() {
+				available = false
+				down = append(down, h.String())
+			}
+		}
+		if !available {
+			continue
+		}
+
+		p.mu.Lock()
+		name, named := realm.name, realm.named
+		p.mu.Unlock()
+		if !named {
+			down = append(down, fmt.Sprintf("%s (name of realm %d)", p.auth, realm.id))
+			continue
+		}
+		playerbots := realm.playerbots.querier()
+		conns.Realms = append(conns.Realms, &Realm{
+			ID:             realm.id,
+			Name:           name,
+			Characters:     realm.characters.db,
+			World:          realm.world.db,
+			Playerbots:     playerbots,
+			PlayerbotsDown: realm.playerbots != nil && playerbots == nil,
+			Names:          realm.names.Load(),
+		})
+	}
+
+	if needsRealm && len(conns.Realms) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, strings.Join(down, ", "))
+	}
+	return conns, nil
+}
+
+// querier returns the connection pool of an optional database, or nil when
+// it is not configured or down
+func (h *handle) querier() Querier {
+	if h == nil || !h.up.Load() {
+		return nil
+	}
+	return h.db
+}
+
+// Ping checks every database concurrently and returns the errors of those
+// that did not answer before ctx is done
+func (p *Pool) Ping(ctx context.Context) error {
+	handles := p.handles()
+	errs := make([]error, len(handles))
+	var wg sync.WaitGroup
+	for i, h := range handles {
+		wg.Add(1)
+		go func(i int, h *handle) {
+			defer wg.Done()
+			if err := h.ping(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", h, err)
+			}
+		}(i, h)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Statuses returns the availability and pool statistics of every database.
+// The databases of realms whose name is not known yet are left out.
+func (p *Pool) Statuses() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var statuses []Status
+	for _, h := range p.handles() {
+		status := Status{Database: h.name, Up: h.up.Load(), Stats: h.db.Stats()}
+		if h.realm != nil {
+			if !h.realm.named {
+				continue
+			}
+			status.Realm = h.realm.name
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Close stops checking the databases and closes them
+func (p *Pool) Close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+	p.closeDatabases()
+}
+
+// closeDatabases closes every opened database
+func (p *Pool) closeDatabases() {
+	for _, h := range p.handles() {
+		closeWithLog(h.db, h.String())
+	}
+}
@@ -0,0 +1,324 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/dbc"
+)
+
+// errConnectionRefused is the error the fake databases fail pings with
+var errConnectionRefused = errors.New("connection refused")
+
+// queryMatcher matches queries containing the expected text, ignoring
+// differences in white space
+var queryMatcher = sqlmock.QueryMatcherFunc(func(expected, actual string) error {
+	if !strings.Contains(strings.Join(strings.Fields(actual), " "), strings.Join(strings.Fields(expected), " ")) {
+		return fmt.Errorf("query %q does not contain %q", actual, expected)
+	}
+	return nil
+})
+
+// fakeRealm holds the fakes behind the databases of one realm
+type fakeRealm struct {
+	characters sqlmock.Sqlmock
+	world      sqlmock.Sqlmock
+}
+
+// newFakeHandle returns an available database of the given name backed by a
+// fake that expects its pings to be declared
+func newFakeHandle(t *testing.T, name string, realm *realmHandles) (*handle, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(queryMatcher), sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("creating fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	h := &handle{name: name, realm: realm, db: db}
+	h.up.Store(true)
+	return h, mock
+}
+
+// newTestPool returns a pool of an available auth database and of realms
+// with the given IDs, named by their ID
+func newTestPool(t *testing.T, ids ...int) (*Pool, sqlmock.Sqlmock, []*fakeRealm) {
+	t.Helper()
+	p := &Pool{}
+	auth, authMock := newFakeHandle(t, AuthDatabase, nil)
+	p.auth = auth
+
+	var fakes []*fakeRealm
+	for _, id := range ids {
+		realm := &realmHandles{id: id, name: fmt.Sprint(id), named: true}
+		realm.names.Store(dbc.Default())
+		fake := &fakeRealm{}
+		realm.characters, fake.characters = newFakeHandle(t, CharactersDatabase, realm)
+		realm.world, fake.world = newFakeHandle(t, WorldDatabase, realm)
+		p.realms = append(p.realms, realm)
+		fakes = append(fakes, fake)
+	}
+	return p, authMock, fakes
+}
+
+// realmNames returns the names of the realms of conns
+func realmNames(conns *Connections) []string {
+	var names []string
+	for _, realm := range conns.Realms {
+		names = append(names, realm.Name)
+	}
+	return names
+}
+
+func TestConnectionsLeavesOutRealmsWithDownDatabase(t *testing.T) {
+	p, _, _ := newTestPool(t, 1, 2, 3)
+	p.realms[1].characters.up.Store(false)
+	p.realms[2].world.up.Store(false)
+
+	conns, err := p.Connections(CharactersDatabase)
+	if err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if got := realmNames(conns); strings.Join(got, ",") != "1,3" {
+		t.Errorf("got realms %v for the characters database, want 1 and 3", got)
+	}
+
+	conns, err = p.Connections(CharactersDatabase, WorldDatabase)
+	if err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if got := realmNames(conns); strings.Join(got, ",") != "1" {
+		t.Errorf("got realms %v for the characters and world databases, want 1", got)
+	}
+}
+
+func TestConnectionsOfDownPlayerbotsDatabase(t *testing.T) {
+	p, _, _ := newTestPool(t, 1)
+	realm := p.realms[0]
+	realm.playerbots, _ = newFakeHandle(t, PlayerbotsDatabase, realm)
+	realm.playerbots.up.Store(false)
+
+	// Collectors for which playerbots is optional keep the realm
+	conns, err := p.Connections(CharactersDatabase)
+	if err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if len(conns.Realms) != 1 || conns.Realms[0].Playerbots != nil || !conns.Realms[0].PlayerbotsDown {
+		t.Errorf("got realms %+v, want one without playerbots connection marked down", conns.Realms)
+	}
+
+	if _, err := p.Connections(CharactersDatabase, PlayerbotsDatabase); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("got error %v for the playerbots database, want ErrUnavailable", err)
+	}
+}
+
+func TestConnectionsUnavailable(t *testing.T) {
+	p, _, _ := newTestPool(t, 1, 2)
+	p.auth.up.Store(false)
+	for _, realm := range p.realms {
+		realm.characters.up.Store(false)
+	}
+
+	tests := []struct {
+		name      string
+		databases []string
+		want      string
+	}{
+		{name: "auth", databases: []string{AuthDatabase}, want: "database unavailable: auth"},
+		{name: "every realm", databases: []string{CharactersDatabase}, want: "database unavailable: realm 1 characters, realm 2 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Connections(tt.databases...)
+			if !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("got error %v, want ErrUnavailable", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got error %q, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// The world databases are still available
+	conns, err := p.Connections(WorldDatabase)
+	if err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if len(conns.Realms) != 2 {
+		t.Errorf("got %d realms for the world database, want 2", len(conns.Realms))
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	p, _, realms := newTestPool(t, 1)
+	realms[0].characters.ExpectQuery(`FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()`).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME"}).
+			AddRow("characters", "guid").
+			AddRow("Characters", "Money").
+			AddRow("guild", "guildid"))
+
+	h := p.realms[0].characters
+	h.loadSchema(context.Background())
+	want := Requirement{Database: CharactersDatabase, Table: "CHARACTERS", Columns: []string{"GUID", "money"}}
+	if missing := h.missing(want); missing != "" {
+		t.Errorf("got %s missing, want the columns to match in any case", missing)
+	}
+	if !h.hasTable("guild") || h.hasTable("guild_member") {
+		t.Error("hasTable does not report the loaded tables")
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	characters := func(columns ...string) *schema {
+		tables := schema{"characters": make(map[string]bool)}
+		for _, column := range columns {
+			tables["characters"][column] = true
+		}
+		return &tables
+	}
+	reqs := []Requirement{{Database: CharactersDatabase, Table: "characters", Columns: []string{"guid", "money"}}}
+
+	t.Run("met", func(t *testing.T) {
+		p, _, _ := newTestPool(t, 1, 2)
+		p.realms[0].characters.schema.Store(characters("guid", "money"))
+		// The schema of realm 2 is not loaded and assumed to meet them
+		gaps, err := p.CheckSchema(reqs)
+		if err != nil || len(gaps) != 0 {
+			t.Errorf("got gaps %v and error %v, want none", gaps, err)
+		}
+	})
+
+	t.Run("missing column of one realm", func(t *testing.T) {
+		p, _, _ := newTestPool(t, 1, 2)
+		p.realms[0].characters.schema.Store(characters("guid", "money"))
+		p.realms[1].name = "Northrend"
+		p.realms[1].characters.schema.Store(characters("guid"))
+		gaps, err := p.CheckSchema(reqs)
+		if err != nil {
+			t.Fatalf("got error %v, want realm 1 to meet the requirements", err)
+		}
+		want := []SchemaGap{{RealmID: 2, Realm: "Northrend", Missing: "characters lacks column characters.money"}}
+		if len(gaps) != 1 || gaps[0] != want[0] {
+			t.Errorf("got gaps %+v, want %+v", gaps, want)
+		}
+	})
+
+	t.Run("missing table of every realm", func(t *testing.T) {
+		p, _, _ := newTestPool(t, 1, 2)
+		empty := schema{}
+		p.realms[0].characters.schema.Store(&empty)
+		p.realms[1].characters.schema.Store(characters("guid"))
+		_, err := p.CheckSchema(reqs)
+		want := "realm 1 characters lacks table characters, realm 2 characters lacks column characters.money"
+		if err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+	})
+
+	t.Run("missing table of auth", func(t *testing.T) {
+		p, _, _ := newTestPool(t, 1)
+		empty := schema{}
+		p.auth.schema.Store(&empty)
+		_, err := p.CheckSchema([]Requirement{{Database: AuthDatabase, Table: "uptime"}})
+		if want := "auth lacks table uptime"; err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+	})
+}
+
+func TestCheckLooksUpRealmNamesWhenAuthComesBack(t *testing.T) {
+	p, auth, _ := newTestPool(t, 1)
+	p.realms[0].name, p.realms[0].named = "", false
+	p.auth.up.Store(false)
+	p.lookupRealmNames(context.Background())
+
+	// The realm is left out until its name is known, so that its series
+	// never change labels
+	if _, err := p.Connections(CharactersDatabase); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("got error %v while the realm name is unknown, want ErrUnavailable", err)
+	}
+	if statuses := p.Statuses(); len(statuses) != 1 || statuses[0].Database != AuthDatabase {
+		t.Fatalf("got statuses %+v while the realm name is unknown, want auth only", statuses)
+	}
+
+	// A failed check keeps auth down
+	auth.ExpectPing().WillReturnError(errConnectionRefused)
+	if err := p.check(context.Background(), p.auth); !errors.Is(err, errConnectionRefused) {
+		t.Fatalf("got error %v, want the error of the ping", err)
+	}
+	if p.auth.up.Load() {
+		t.Fatal("auth is up after a failed ping")
+	}
+
+	// Once auth is back the realm names are looked up and its schema loaded
+	auth.ExpectPing()
+	auth.ExpectQuery(`SELECT name FROM realmlist WHERE id = ?`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Azeroth"))
+	auth.ExpectQuery(`FROM information_schema.COLUMNS`).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME"}).AddRow("realmlist", "name"))
+	if err := p.check(context.Background(), p.auth); err != nil {
+		t.Fatalf("checking auth: %v", err)
+	}
+	if !p.auth.up.Load() {
+		t.Error("auth is down after a successful ping")
+	}
+
+	conns, err := p.Connections(CharactersDatabase)
+	if err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if got := realmNames(conns); len(got) != 1 || got[0] != "Azeroth" {
+		t.Errorf("got realms %v, want Azeroth", got)
+	}
+	if statuses := p.Statuses(); statuses[1].Realm != "Azeroth" {
+		t.Errorf("got status of realm %q, want Azeroth", statuses[1].Realm)
+	}
+}
+
+func TestLookupRealmNames(t *testing.T) {
+	p, auth, _ := newTestPool(t, 1, 2, 3)
+	// Realm 1 has a configured name, which is never looked up
+	p.realms[0].name = "Configured"
+	p.realms[1].name, p.realms[1].named = "", false
+	p.realms[2].name, p.realms[2].named = "", false
+
+	auth.ExpectQuery(`SELECT name FROM realmlist WHERE id = ?`).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	auth.ExpectQuery(`SELECT name FROM realmlist WHERE id = ?`).WithArgs(3).
+		WillReturnError(errConnectionRefused)
+	p.lookupRealmNames(context.Background())
+
+	// Realm 2 is missing from the realmlist and named by its ID, realm 3 is
+	// left out until its lookup succeeds
+	conns, err := p.Connections(CharactersDatabase)
+	if err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if got := realmNames(conns); strings.Join(got, ",") != "Configured,2" {
+		t.Errorf("got realms %v, want Configured and 2", got)
+	}
+
+	auth.ExpectPing()
+	auth.ExpectQuery(`SELECT name FROM realmlist WHERE id = ?`).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Outland"))
+	auth.ExpectQuery(`FROM information_schema.COLUMNS`).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME"}).AddRow("realmlist", "name"))
+	if err := p.check(context.Background(), p.auth); err != nil {
+		t.Fatalf("checking auth: %v", err)
+	}
+	if conns, err = p.Connections(CharactersDatabase); err != nil {
+		t.Fatalf("getting connections: %v", err)
+	}
+	if got := realmNames(conns); strings.Join(got, ",") != "Configured,2,Outland" {
+		t.Errorf("got realms %v, want Configured, 2 and Outland", got)
+	}
+}
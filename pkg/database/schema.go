@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// schemaTimeout bounds a single load of the schema of a database
+const schemaTimeout = 30 * time.Second
+
+// Requirement is a table a collector reads and the columns it uses from it
+type Requirement struct {
+	// Database is the database holding the table: auth, characters, world
+	// or playerbots
+	Database string
+	Table    string
+	Columns  []string
+}
+
+// schema maps the tables of a database to their columns, all in lower case
+type schema map[string]map[string]bool
+
+// missing describes the first part of req the schema lacks, or returns an
+// empty string when the schema has the table and all of its columns
+func (s schema) missing(req Requirement) string {
+	columns, ok := s[strings.ToLower(req.Table)]
+	if !ok {
+		return "table " + req.Table
+	}
+	for _, column := range req.Columns {
+		if !columns[strings.ToLower(column)] {
+			return "column " + req.Table + "." + column
+		}
+	}
+	return ""
+}
+
+// loadSchema reads the tables and columns of the database from
+// information_schema and stores them. A failed load keeps the previous
+// schema.
+func (h *handle) loadSchema(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, schemaTimeout)
+	defer cancel()
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+	`)
+	if err != nil {
+		slog.Warn("Could not load database schema", append(h.attrs(), "err", err)...)
+		return
+	}
+	defer CloseRowsWithLog(rows)
+
+	tables := make(schema)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			slog.Warn("Could not load database schema", append(h.attrs(), "err", err)...)
+			return
+		}
+		table = strings.ToLower(table)
+		if tables[table] == nil {
+			tables[table] = make(map[string]bool)
+		}
+		tables[table][strings.ToLower(column)] = true
+	}
+	if err := rows.Err(); err != nil {
+		slog.Warn("Could not load database schema", append(h.attrs(), "err", err)...)
+		return
+	}
+
+	h.schema.Store(&tables)
+	h.schemaLoaded = time.Now()
+	slog.Debug("Loaded database schema", append(h.attrs(), "tables", len(tables))...)
+}
+
+// hasTable reports whether the database has the table. It returns true
+// while the schema is not loaded.
+func (h *handle) hasTable(table string) bool {
+	tables := h.schema.Load()
+	if tables == nil {
+		return true
+	}
+	_, ok := (*tables)[strings.ToLower(table)]
+	return ok
+}
+
+// SchemaGap is a realm whose databases lack a table or column a collector
+// needs
+type SchemaGap struct {
+	RealmID int
+	// Realm is empty while the name of the realm is not known
+	Realm string
+	// Missing describes the first requirement the realm lacks
+	Missing string
+}
+
+// CheckSchema checks the requirements of a collector. Auth requirements are
+// checked against the auth database and realm requirements against the
+// databases of each realm. It returns the realms lacking a requirement,
+// which the collector leaves out, and an error describing what is missing
+// when the auth database or every realm lacks one. Databases whose schema
+// has not been loaded yet, such as those unavailable since startup, and
+// realms without a playerbots database, are assumed to meet them.
+func (p *Pool) CheckSchema(reqs []Requirement) ([]SchemaGap, error) {
+	for _, req := range reqs {
+		if req.Database != AuthDatabase {
+			continue
+		}
+		if missing := p.auth.missing(req); missing != "" {
+			return nil, fmt.Errorf("%s lacks %s", p.auth, missing)
+		}
+	}
+
+	var gaps []SchemaGap
+	var reasons []string
+	for _, realm := range p.realms {
+		missing := realm.missing(reqs)
+		if missing == "" {
+			continue
+		}
+		var name string
+		p.mu.Lock()
+		if realm.named {
+			name = realm.name
+		}
+		p.mu.Unlock()
+		gaps = append(gaps, SchemaGap{RealmID: realm.id, Realm: name, Missing: missing})
+		reasons = append(reasons, fmt.Sprintf("realm %d %s", realm.id, missing))
+	}
+	if len(gaps) > 0 && len(gaps) == len(p.realms) {
+		return nil, errors.New(strings.Join(reasons, ", "))
+	}
+	return gaps, nil
+}
+
+// missing describes the first of reqs the databases of the realm lack,
+// prefixed by the name of the database, or returns an empty string when
+// they meet them all
+func (r *realmHandles) missing(reqs []Requirement) string {
+	for _, req := range reqs {
+		for _, h := range []*handle{r.characters, r.world, r.playerbots} {
+			if h == nil || h.name != req.Database {
+				continue
+			}
+			if missing := h.missing(req); missing != "" {
+				return h.name + " lacks " + missing
+			}
+		}
+	}
+	return ""
+}
+
+// missing describes the part of req the database lacks, or returns an
+// empty string when it has it or its schema is not loaded
+func (h *handle) missing(req Requirement) string {
+	tables := h.schema.Load()
+	if tables == nil {
+		return ""
+	}
+	return tables.missing(req)
+}
@@ -0,0 +1,201 @@
+// Package dbc resolves the race, class, map, battleground and area IDs
+// stored in the databases to names, using the DBC tables of the world
+// database.
+package dbc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/scottjab/prom-azerothcore-exporter/pkg/constants"
+)
+
+// mapTypeBattleground is the map_dbc InstanceType of battleground maps
+const mapTypeBattleground = 3
+
+// Names maps game IDs to the names used in metric labels
+type Names struct {
+	factions      map[int]string
+	classes       map[int]string
+	maps          map[int]string
+	bgMaps        map[int]bool
+	battlegrounds map[int]string
+	areas         map[int]string
+}
+
+// Default returns the built-in WotLK names
+func Default() *Names {
+	n := &Names{
+		factions:      maps.Clone(constants.RaceToFaction),
+		classes:       maps.Clone(constants.ClassNames),
+		maps:          maps.Clone(constants.BattlegroundMaps),
+		bgMaps:        make(map[int]bool),
+		battlegrounds: maps.Clone(constants.BattlegroundTypeNames),
+		areas:         make(map[int]string),
+	}
+	for id := range constants.BattlegroundMaps {
+		n.bgMaps[id] = true
+	}
+	return n
+}
+
+// table loads one DBC table into names
+type table struct {
+	name  string
+	query string
+	load  func(n *Names, rows *sql.Rows) error
+}
+
+var tables = []table{
+	{
+		name:  "chrraces_dbc",
+		query: `SELECT ID, Alliance FROM chrraces_dbc`,
+		load: func(n *Names, rows *sql.Rows) error {
+			var id, alliance int
+			if err := rows.Scan(&id, &alliance); err != nil {
+				return err
+			}
+			switch alliance {
+			case 0:
+				n.factions[id] = "Alliance"
+			case 1:
+				n.factions[id] = "Horde"
+			default:
+				delete(n.factions, id)
+			}
+			return nil
+		},
+	},
+	{
+		name:  "chrclasses_dbc",
+		query: `SELECT ID, Name_Lang_enUS FROM chrclasses_dbc`,
+		load: func(n *Names, rows *sql.Rows) error {
+			return scanName(rows, n.classes)
+		},
+	},
+	{
+		name:  "map_dbc",
+		query: `SELECT ID, MapName_Lang_enUS, InstanceType FROM map_dbc`,
+		load: func(n *Names, rows *sql.Rows) error {
+			var id, instanceType int
+			var name sql.NullString
+			if err := rows.Scan(&id, &name, &instanceType); err != nil {
+				return err
+			}
+			if name.String != "" {
+				n.maps[id] = name.String
+			}
+			n.bgMaps[id] = instanceType == mapTypeBattleground
+			return nil
+		},
+	},
+	{
+		name:  "battlemasterlist_dbc",
+		query: `SELECT ID, Name_Lang_enUS FROM battlemasterlist_dbc`,
+		load: func(n *Names, rows *sql.Rows) error {
+			return scanName(rows, n.battlegrounds)
+		},
+	},
+	{
+		name:  "areatable_dbc",
+		query: `SELECT ID, AreaName_Lang_enUS FROM areatable_dbc`,
+		load: func(n *Names, rows *sql.Rows) error {
+			return scanName(rows, n.areas)
+		},
+	},
+}
+
+// scanName stores the ID and name of the current row in names, ignoring
+// NULL and empty names
+func scanName(rows *sql.Rows, names map[int]string) error {
+	var id int
+	var name sql.NullString
+	if err := rows.Scan(&id, &name); err != nil {
+		return err
+	}
+	if name.String != "" {
+		names[id] = name.String
+	}
+	return nil
+}
+
+// Load reads the DBC tables of a world database over the built-in names.
+// AzerothCore keeps these tables empty unless a module adds or overrides
+// entries, so rows found replace or extend the defaults. Tables for which
+// exists returns false are skipped. The names are always returned, along
+// with the errors of the tables that could not be read.
+func Load(ctx context.Context, db *sql.DB, exists func(table string) bool) (*Names, error) {
+	n := Default()
+	var errs []error
+	for _, t := range tables {
+		if !exists(t.name) {
+			continue
+		}
+		if err := n.load(ctx, db, t); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// load reads one table into n
+func (n *Names) load(ctx context.Context, db *sql.DB, t table) error {
+	rows, err := db.QueryContext(ctx, t.query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := t.load(n, rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Faction returns the faction of a race, or an empty string for races that
+// belong to neither faction
+func (n *Names) Faction(race int) string {
+	return n.factions[race]
+}
+
+// Class returns the name of a class, or an empty string for unknown classes
+func (n *Names) Class(class int) string {
+	return n.classes[class]
+}
+
+// Map returns the name of a map, or an empty string for unknown maps
+func (n *Names) Map(id int) string {
+	return n.maps[id]
+}
+
+// Area returns the name of a zone or area, or an empty string for unknown
+// areas
+func (n *Names) Area(id int) string {
+	return n.areas[id]
+}
+
+// Battleground returns the name of a battleground type
+func (n *Names) Battleground(bgType int) string {
+	if name, ok := n.battlegrounds[bgType]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown_%d", bgType)
+}
+
+// BattlegroundMaps returns the IDs of the battleground maps in ascending order
+func (n *Names) BattlegroundMaps() []int {
+	var ids []int
+	for id, bg := range n.bgMaps {
+		if bg {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,65 @@
+package dbc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadIgnoresNullNames(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("creating fake database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT ID, Alliance FROM chrraces_dbc`).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Alliance"}))
+	mock.ExpectQuery(`SELECT ID, Name_Lang_enUS FROM chrclasses_dbc`).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name_Lang_enUS"}).
+			AddRow(1, nil).
+			AddRow(12, "Tinker"))
+	mock.ExpectQuery(`SELECT ID, MapName_Lang_enUS, InstanceType FROM map_dbc`).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "MapName_Lang_enUS", "InstanceType"}).
+			AddRow(489, nil, 3).
+			AddRow(1000, "Custom Gulch", 3))
+	mock.ExpectQuery(`SELECT ID, Name_Lang_enUS FROM battlemasterlist_dbc`).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name_Lang_enUS"}).
+			AddRow(2, nil))
+	mock.ExpectQuery(`SELECT ID, AreaName_Lang_enUS FROM areatable_dbc`).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "AreaName_Lang_enUS"}).
+			AddRow(1519, nil).
+			AddRow(5000, "Custom Vale"))
+
+	n, err := Load(context.Background(), db, func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("loading names: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	defaults := Default()
+	if got, want := n.Class(1), defaults.Class(1); got != want {
+		t.Errorf("class 1: got %q, want the default %q", got, want)
+	}
+	if got := n.Class(12); got != "Tinker" {
+		t.Errorf("class 12: got %q, want %q", got, "Tinker")
+	}
+	if got, want := n.Map(489), defaults.Map(489); got != want {
+		t.Errorf("map 489: got %q, want the default %q", got, want)
+	}
+	if got := n.Map(1000); got != "Custom Gulch" {
+		t.Errorf("map 1000: got %q, want %q", got, "Custom Gulch")
+	}
+	if got, want := n.Battleground(2), defaults.Battleground(2); got != want {
+		t.Errorf("battleground 2: got %q, want the default %q", got, want)
+	}
+	if got := n.Area(1519); got != "" {
+		t.Errorf("area 1519: got %q, want none", got)
+	}
+	if got := n.Area(5000); got != "Custom Vale" {
+		t.Errorf("area 5000: got %q, want %q", got, "Custom Vale")
+	}
+}
@@ -0,0 +1,106 @@
+// Package redact hides player-identifying label values such as character
+// names, account names and IP addresses according to the configured mode of
+// each label class.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+// Redaction modes
+const (
+	// Off exports values unchanged
+	Off = "off"
+	// Hash replaces values with a salted HMAC, stable across scrapes
+	Hash = "hash"
+	// Truncate keeps the first character of names and the /24 network of
+	// IPv4 or the /48 network of IPv6 addresses
+	Truncate = "truncate"
+	// Drop replaces values with an empty string, which Prometheus treats as
+	// an absent label
+	Drop = "drop"
+)
+
+// hashLength is the number of hex digits kept from a hashed value
+const hashLength = 16
+
+// Redactor redacts label values by class
+type Redactor struct {
+	key       []byte
+	character string
+	account   string
+	ip        string
+}
+
+// New creates a redactor from the redaction configuration. Modes are
+// validated by config.Load; unknown modes behave as Off.
+func New(cfg config.RedactionConfig) *Redactor {
+	return &Redactor{
+		key:       []byte(cfg.Salt),
+		character: cfg.Character,
+		account:   cfg.Account,
+		ip:        cfg.IP,
+	}
+}
+
+// Character redacts a character name
+func (r *Redactor) Character(name string) string {
+	return r.redactName(r.character, "character", name)
+}
+
+// Account redacts an account name
+func (r *Redactor) Account(name string) string {
+	return r.redactName(r.account, "account", name)
+}
+
+// IP redacts an IP address. Values that are not IP addresses are dropped
+// when truncating.
+func (r *Redactor) IP(ip string) string {
+	switch r.ip {
+	case Hash:
+		return r.hash("ip", ip)
+	case Truncate:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return ""
+		}
+		if v4 := parsed.To4(); v4 != nil {
+			return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+		}
+		return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+	case Drop:
+		return ""
+	default:
+		return ip
+	}
+}
+
+// redactName redacts a name of the given class with mode
+func (r *Redactor) redactName(mode, class, name string) string {
+	switch mode {
+	case Hash:
+		return r.hash(class, name)
+	case Truncate:
+		for _, first := range name {
+			return string(first)
+		}
+		return ""
+	case Drop:
+		return ""
+	default:
+		return name
+	}
+}
+
+// hash returns the truncated HMAC-SHA256 of value. The class is part of the
+// message so equal values of different classes do not correlate.
+func (r *Redactor) hash(class, value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(class + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))[:hashLength]
+}
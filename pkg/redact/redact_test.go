@@ -0,0 +1,80 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/scottjab/prom-azerothcore-exporter/config"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		mode      string
+		character string
+		account   string
+	}{
+		{mode: Off, character: "Arthas", account: "LICHKING"},
+		{mode: Hash, character: "6850a68bf6451f63", account: "de3778b0a87cf22b"},
+		{mode: Truncate, character: "A", account: "L"},
+		{mode: Drop, character: "", account: ""},
+		{mode: "unknown", character: "Arthas", account: "LICHKING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			r := New(config.RedactionConfig{Salt: "secret", Character: tt.mode, Account: tt.mode})
+			if got := r.Character("Arthas"); got != tt.character {
+				t.Errorf("Character: got %q, want %q", got, tt.character)
+			}
+			if got := r.Account("LICHKING"); got != tt.account {
+				t.Errorf("Account: got %q, want %q", got, tt.account)
+			}
+		})
+	}
+}
+
+func TestTruncateMultibyteName(t *testing.T) {
+	r := New(config.RedactionConfig{Character: Truncate})
+	if got := r.Character("Ærin"); got != "Æ" {
+		t.Errorf("got %q, want %q", got, "Æ")
+	}
+	if got := r.Character(""); got != "" {
+		t.Errorf("got %q for an empty name, want an empty string", got)
+	}
+}
+
+func TestHashSeparatesClassesAndSalts(t *testing.T) {
+	r := New(config.RedactionConfig{Salt: "secret", Character: Hash, Account: Hash})
+	if r.Character("Arthas") == r.Account("Arthas") {
+		t.Error("equal character and account names hash to the same value")
+	}
+	other := New(config.RedactionConfig{Salt: "other", Character: Hash})
+	if r.Character("Arthas") == other.Character("Arthas") {
+		t.Error("different salts hash a name to the same value")
+	}
+}
+
+func TestIP(t *testing.T) {
+	tests := []struct {
+		mode string
+		ip   string
+		want string
+	}{
+		{mode: Off, ip: "203.0.113.7", want: "203.0.113.7"},
+		{mode: Off, ip: "2001:db8::1", want: "2001:db8::1"},
+		{mode: Hash, ip: "203.0.113.7", want: "3e1e58e518fad689"},
+		{mode: Hash, ip: "2001:db8::1", want: "3e2a32882fe5a3cd"},
+		{mode: Truncate, ip: "203.0.113.7", want: "203.0.113.0/24"},
+		{mode: Truncate, ip: "::ffff:203.0.113.7", want: "203.0.113.0/24"},
+		{mode: Truncate, ip: "2001:db8:1234:5678::1", want: "2001:db8:1234::/48"},
+		{mode: Truncate, ip: "not an ip", want: ""},
+		{mode: Drop, ip: "203.0.113.7", want: ""},
+		{mode: Drop, ip: "2001:db8::1", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.ip, func(t *testing.T) {
+			r := New(config.RedactionConfig{Salt: "secret", IP: tt.mode})
+			if got := r.IP(tt.ip); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}